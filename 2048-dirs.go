@@ -0,0 +1,228 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+//appName is name of directories of game in user data and config directories
+const appName = "2048"
+
+//environment variables which override directories of game
+const (
+	envDataDir   = "GAME2048_DATA"
+	envConfigDir = "GAME2048_CONFIG"
+)
+
+var (
+	//dataDir contains saves, saved games and leaderboard
+	dataDir string
+
+	//configDir contains settings of game
+	configDir string
+)
+
+//defaultDataDir return $XDG_DATA_HOME/2048, by default it is ~/.local/share/2048,
+//on windows and osx user config directory is used
+func defaultDataDir() string {
+	if dir := os.Getenv(envDataDir); dir != "" {
+		return dir
+	}
+
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, appName)
+		}
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", appName)
+	}
+
+	return exeDir()
+}
+
+//defaultConfigDir return $XDG_CONFIG_HOME/2048, by default it is ~/.config/2048
+func defaultConfigDir() string {
+	if dir := os.Getenv(envConfigDir); dir != "" {
+		return dir
+	}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, appName)
+	}
+
+	return exeDir()
+}
+
+//exeDir return directory of binary, before per-user directories all files were kept there
+func exeDir() string {
+	if exe, err := os.Executable(); err == nil {
+		return filepath.Dir(exe)
+	}
+	return filepath.Dir(os.Args[0])
+}
+
+//SetupDirs create data and config directories and point files of game to them,
+//on first run files are moved from directory of binary
+func SetupDirs(data, config string) error {
+	firstRun := false
+	if _, err := os.Stat(data); os.IsNotExist(err) {
+		firstRun = true
+	}
+
+	for _, dir := range []string{data, config} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	dataDir, configDir = data, config
+
+	saveFilename = filepath.Join(dataDir, "2048.save")
+	leaderboardFilename = filepath.Join(dataDir, "leaderboard")
+	slotsDir = filepath.Join(dataDir, "saves")
+	positionsDir = filepath.Join(dataDir, "positions")
+	replayFilename = filepath.Join(dataDir, "2048.replay")
+	statsFilename = filepath.Join(dataDir, "statistics")
+	settingsFilename = filepath.Join(configDir, "settings.json")
+	queueFilename = filepath.Join(dataDir, "submissions")
+	profilesDir = filepath.Join(dataDir, "profiles")
+
+	if firstRun {
+		migrateDir(exeDir(), dataDir)
+	}
+
+	fontfilename = findFont(fontfilename)
+
+	return nil
+}
+
+//migrateDir move save, saved games and leaderboard with their backups from old directory of game
+func migrateDir(old, dir string) {
+	if same(old, dir) {
+		return
+	}
+
+	names := withBackups("2048.save", "2048.replay", "leaderboard")
+
+	if files, err := ioutil.ReadDir(filepath.Join(old, "saves")); err == nil {
+		os.MkdirAll(filepath.Join(dir, "saves"), 0755)
+		for _, fi := range files {
+			names = append(names, filepath.Join("saves", fi.Name()))
+		}
+	}
+
+	moveFiles(old, dir, names)
+}
+
+//withBackups return names of files followed by names of their backups
+func withBackups(files ...string) (names []string) {
+	for _, name := range files {
+		names = append(names, name)
+		for n := 1; n <= backupCount; n++ {
+			names = append(names, backupName(name, n))
+		}
+	}
+	return
+}
+
+//moveFiles move existing files from old directory to dir, names are relative to directories
+func moveFiles(old, dir string, names []string) {
+	for _, name := range names {
+		src, dst := filepath.Join(old, name), filepath.Join(dir, name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		if err := moveFile(src, dst); err != nil {
+			log.Printf("failed move %s to %s, %s", src, dst, err)
+			continue
+		}
+		log.Printf("%s is moved to %s", src, dst)
+	}
+}
+
+//same return true if both paths point to the same directory
+func same(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fa, fb)
+}
+
+//moveFile rename file, if it is not possible (other device or read-only directory) then file is copied
+func moveFile(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return os.ErrExist
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	//old file may be kept if directory of binary is read-only
+	os.Remove(src)
+	return nil
+}
+
+//findFont look for font next to binary, in data directory and in system data directories
+func findFont(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	dirs := []string{".", exeDir(), dataDir}
+
+	sysDirs := os.Getenv("XDG_DATA_DIRS")
+	if sysDirs == "" {
+		sysDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(sysDirs) {
+		dirs = append(dirs, filepath.Join(dir, appName))
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return name
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sg3des/2048/engine"
+)
+
+//csvColumns is header of leaderboard in CSV, replay is encoded in base64
+var csvColumns = []string{"name", "profile", "score", "rows", "cols", "won", "date", "duration", "max_tile", "moves", "seed", "policy", "target", "undo", "undone", "replay"}
+
+//WriteCSV write results as CSV with header csvColumns, date is in RFC 3339 and duration in seconds
+func (lb LeaderBoard) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvColumns)
+
+	for _, u := range lb.Users {
+		cw.Write([]string{
+			u.Name,
+			u.Profile,
+			strconv.Itoa(u.Score),
+			strconv.Itoa(u.Rows),
+			strconv.Itoa(u.Cols),
+			strconv.FormatBool(u.Won),
+			u.Date.Format(time.RFC3339),
+			strconv.FormatFloat(u.Duration.Seconds(), 'f', -1, 64),
+			strconv.Itoa(u.MaxTile),
+			strconv.Itoa(u.Moves),
+			strconv.FormatInt(u.Seed, 10),
+			u.Rules.Policy,
+			strconv.Itoa(u.Rules.Target),
+			strconv.Itoa(u.Rules.Undo),
+			strconv.FormatBool(u.Undone),
+			base64.StdEncoding.EncodeToString(u.Replay),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+//ReadCSV read results from CSV, columns are found by names of header, so their order does not matter,
+//missing columns are left empty. Columns which can be calculated from replay are filled on verification.
+func ReadCSV(r io.Reader) (lb LeaderBoard, err error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return
+	}
+	if len(records) == 0 {
+		return lb, fmt.Errorf("CSV has no header")
+	}
+
+	index := make(map[string]int)
+	for i, name := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for line, record := range records[1:] {
+		u, err := parseCSVUser(index, record)
+		if err != nil {
+			return lb, fmt.Errorf("line %d: %s", line+2, err)
+		}
+		lb.Users = append(lb.Users, u)
+	}
+
+	return
+}
+
+//parseCSVUser parse result from record of CSV, index is number of column by its name
+func parseCSVUser(index map[string]int, record []string) (u User, err error) {
+	field := func(name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	parse := func(name string, f func(s string) error) {
+		if s := field(name); s != "" && err == nil {
+			if e := f(s); e != nil {
+				err = fmt.Errorf("invalid %s %q", name, s)
+			}
+		}
+	}
+
+	num := func(name string, n *int) {
+		parse(name, func(s string) (e error) { *n, e = strconv.Atoi(s); return })
+	}
+
+	boolean := func(name string, b *bool) {
+		parse(name, func(s string) (e error) { *b, e = strconv.ParseBool(s); return })
+	}
+
+	u.Name = field("name")
+	u.Profile = field("profile")
+	u.Rules.Policy = field("policy")
+	num("score", &u.Score)
+	num("rows", &u.Rows)
+	num("cols", &u.Cols)
+	num("max_tile", &u.MaxTile)
+	num("moves", &u.Moves)
+	num("target", &u.Rules.Target)
+	num("undo", &u.Rules.Undo)
+	boolean("won", &u.Won)
+	boolean("undone", &u.Undone)
+	parse("date", func(s string) (e error) { u.Date, e = time.Parse(time.RFC3339, s); return })
+	parse("seed", func(s string) (e error) { u.Seed, e = strconv.ParseInt(s, 10, 64); return })
+	parse("replay", func(s string) (e error) { u.Replay, e = base64.StdEncoding.DecodeString(s); return })
+	parse("duration", func(s string) error {
+		sec, e := strconv.ParseFloat(s, 64)
+		u.Duration = time.Duration(sec * float64(time.Second))
+		return e
+	})
+
+	//size of board is checked on verification, so missing rows and cols are taken from replay
+	if u.Rows == 0 && u.Cols == 0 && err == nil {
+		r := new(engine.Replay)
+		if r.UnmarshalBinary(u.Replay) == nil {
+			u.Rows, u.Cols = r.Rows, r.Cols
+		}
+	}
+
+	return
+}
+
+//jsonUser is result in JSON, duration is in seconds as in CSV
+type jsonUser struct {
+	user
+	Duration float64 `json:"duration"`
+}
+
+//user is User without methods of JSON encoding
+type user User
+
+//MarshalJSON encode result with duration in seconds, it is used by export and API of server
+func (u User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonUser{user(u), u.Duration.Seconds()})
+}
+
+//UnmarshalJSON decode result written by MarshalJSON
+func (u *User) UnmarshalJSON(data []byte) error {
+	var ju jsonUser
+	if err := json.Unmarshal(data, &ju); err != nil {
+		return err
+	}
+
+	*u = User(ju.user)
+	u.Duration = time.Duration(ju.Duration * float64(time.Second))
+	return nil
+}
+
+//WriteJSON write results as indented JSON array, replay is encoded in base64 and duration is in seconds
+func (lb LeaderBoard) WriteJSON(w io.Writer) error {
+	users := lb.Users
+	if users == nil {
+		users = []User{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(users)
+}
+
+//ReadJSON read results from JSON array written by WriteJSON
+func ReadJSON(r io.Reader) (lb LeaderBoard, err error) {
+	err = json.NewDecoder(r).Decode(&lb.Users)
+	return
+}
+
+//leaderboardFormat return format of leaderboard file by its extension: csv, json or native format of game
+func leaderboardFormat(filename string) string {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".csv", ".json":
+		return ext[1:]
+	}
+	return "native"
+}
+
+//ReadLeaderBoardFile read results from CSV, JSON or native leaderboard file, format is chosen by extension.
+//Results of file are imported from outside, so results which are not confirmed by their replays are dropped.
+func ReadLeaderBoardFile(filename string) (lb LeaderBoard, err error) {
+	format := leaderboardFormat(filename)
+	if format == "native" {
+		lb, err = readLeaderBoard(filename)
+	} else {
+		lb, err = readLeaderBoardText(filename, format)
+	}
+	if err != nil {
+		return
+	}
+
+	lb.Users = lb.Verified()
+	return
+}
+
+//readLeaderBoardText read results from CSV or JSON file
+func readLeaderBoardText(filename, format string) (lb LeaderBoard, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if format == "csv" {
+		lb, err = ReadCSV(f)
+	} else {
+		lb, err = ReadJSON(f)
+	}
+	if err != nil {
+		return lb, fmt.Errorf("%s: %s", filename, err)
+	}
+	return
+}
+
+//encodeLeaderBoard write results in format csv or json
+func encodeLeaderBoard(w io.Writer, lb LeaderBoard, format string) error {
+	switch format {
+	case "csv":
+		return lb.WriteCSV(w)
+	case "json":
+		return lb.WriteJSON(w)
+	}
+	return fmt.Errorf("unknown format %q, it should be csv or json", format)
+}
+
+//WriteLeaderBoardFile write results to CSV, JSON or native leaderboard file, format is chosen by extension
+func WriteLeaderBoardFile(filename string, lb LeaderBoard) error {
+	format := leaderboardFormat(filename)
+	if format == "native" {
+		return lb.Write(filename)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeLeaderBoard(&buf, lb, format); err != nil {
+		return err
+	}
+	return WriteFileAtomic(filename, buf.Bytes(), 0644)
+}
+
+//MergeLeaderBoardFiles read and merge results of files, the same results are kept once,
+//limit the best results of every mode are kept ordered from the best one
+func MergeLeaderBoardFiles(filenames []string, limit int) (lb LeaderBoard, err error) {
+	for _, filename := range filenames {
+		other, err := ReadLeaderBoardFile(filename)
+		if err != nil {
+			return lb, err
+		}
+		lb.Merge(other)
+	}
+
+	lb.Trim(limit)
+	return
+}
+
+//ExportLeaderBoard is command export-leaderboard, it writes leaderboard of data directory as CSV or JSON
+func ExportLeaderBoard(args []string) int {
+	fs := flag.NewFlagSet("export-leaderboard", flag.ExitOnError)
+	data := fs.String("data", defaultDataDir(), "data directory of game with leaderboard")
+	out := fs.String("o", "", "output file, format is chosen by extension .csv or .json, by default results are written to stdout")
+	format := fs.String("format", "json", "format of output to stdout, csv or json")
+	fs.Parse(args)
+
+	lb, _, err := loadLeaderBoardFile(filepath.Join(*data, "leaderboard"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("failed read leaderboard,", err)
+		return 1
+	}
+	sort.Sort(sort.Reverse(lb))
+
+	if *out == "" {
+		err = encodeLeaderBoard(os.Stdout, lb, *format)
+	} else if leaderboardFormat(*out) == "native" {
+		err = fmt.Errorf("unknown format of %s, extension should be .csv or .json", *out)
+	} else {
+		err = WriteLeaderBoardFile(*out, lb)
+	}
+	if err != nil {
+		log.Println("failed export leaderboard,", err)
+		return 1
+	}
+
+	return 0
+}
+
+//ImportLeaderBoard is command import-leaderboard, it adds results of CSV, JSON or leaderboard files to leaderboard of data directory
+func ImportLeaderBoard(args []string) int {
+	fs := flag.NewFlagSet("import-leaderboard", flag.ExitOnError)
+	data := fs.String("data", defaultDataDir(), "data directory of game with leaderboard")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: 2048 import-leaderboard [-data dir] file.csv|file.json|leaderboard ...")
+		return 2
+	}
+
+	lb, err := MergeLeaderBoardFiles(fs.Args(), leaderboardTop)
+	if err != nil {
+		log.Println("failed import leaderboard,", err)
+		return 1
+	}
+
+	if err := os.MkdirAll(*data, 0755); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	filename := filepath.Join(*data, "leaderboard")
+	stored, err := ReadLeaderBoard(filename)
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("failed read leaderboard,", err)
+		return 1
+	}
+
+	if _, err := lb.Update(filename, leaderboardTop); err != nil {
+		log.Println("failed write leaderboard,", err)
+		return 1
+	}
+
+	//results which leaderboard already has are not counted as imported
+	fmt.Printf("%d results are imported, leaderboard contains %d results\n", len(lb.Users)-len(stored.Users), len(lb.Users))
+	return 0
+}
+
+//MergeLeaderBoard is command merge-leaderboard, it merges CSV, JSON or leaderboard files into one file
+func MergeLeaderBoard(args []string) int {
+	fs := flag.NewFlagSet("merge-leaderboard", flag.ExitOnError)
+	out := fs.String("o", "", "output file, format is chosen by extension .csv, .json or native format of leaderboard otherwise")
+	top := fs.Int("top", leaderboardTop, "count of results kept for every mode")
+	fs.Parse(args)
+
+	if *out == "" || fs.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "usage: 2048 merge-leaderboard -o output [-top N] file1 file2 ...")
+		return 2
+	}
+
+	lb, err := MergeLeaderBoardFiles(fs.Args(), *top)
+	if err != nil {
+		log.Println("failed merge leaderboards,", err)
+		return 1
+	}
+
+	if err := WriteLeaderBoardFile(*out, lb); err != nil {
+		log.Println("failed write leaderboard,", err)
+		return 1
+	}
+
+	fmt.Printf("%d results are written to %s\n", len(lb.Users), *out)
+	return 0
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//backupCount is count of rotated backups kept next to save and leaderboard files
+const backupCount = 3
+
+//backupInterval is minimal time between rotations of backups, file may be written on every move,
+//so backups keep states of file from different moments instead of the last few writes
+var backupInterval = 10 * time.Minute
+
+//checkedMagic starts files written with checksum, files without it are written by previous versions
+var checkedMagic = []byte("2048CRC1")
+
+//ErrChecksum is returned when content of file does not match its checksum
+var ErrChecksum = errors.New("checksum mismatch, file is damaged")
+
+//WriteFileAtomic write data to temporary file in the same directory, flush it to disk and rename to filename,
+//so filename always contains either old or new content
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+
+	f, err := ioutil.TempFile(dir, filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	//sync directory to persist rename, it is not supported on some systems
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+//backupName return name of n-th backup of file
+func backupName(filename string, n int) string {
+	return fmt.Sprintf("%s.%d", filename, n)
+}
+
+//backupDue return true if the first backup of file is missing or older than backupInterval
+func backupDue(filename string) bool {
+	fi, err := os.Stat(backupName(filename, 1))
+	return err != nil || time.Since(fi.ModTime()) >= backupInterval
+}
+
+//rotateBackups shift backups of file, the oldest one is removed and current file becomes the first backup.
+//File itself is copied to backup, so it is never missing.
+func rotateBackups(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	for n := backupCount - 1; n > 0; n-- {
+		os.Rename(backupName(filename, n), backupName(filename, n+1))
+	}
+	return WriteFileAtomic(backupName(filename, 1), data, 0644)
+}
+
+//encodeChecked prepend data with magic, length and checksum
+func encodeChecked(data []byte) []byte {
+	buf := make([]byte, len(checkedMagic)+12, len(checkedMagic)+12+len(data))
+	copy(buf, checkedMagic)
+	binary.BigEndian.PutUint64(buf[len(checkedMagic):], uint64(len(data)))
+	binary.BigEndian.PutUint32(buf[len(checkedMagic)+8:], crc32.ChecksumIEEE(data))
+	return append(buf, data...)
+}
+
+//decodeChecked verify checksum and return data. Content without magic is returned as is only if legacy accepts it,
+//so file with damaged magic is not mistaken for file of previous version. Nil legacy means that file has no previous format.
+func decodeChecked(buf []byte, legacy func(data []byte) error) ([]byte, error) {
+	if !bytes.HasPrefix(buf, checkedMagic) {
+		if legacy == nil || legacy(buf) != nil {
+			return nil, ErrChecksum
+		}
+		return buf, nil
+	}
+
+	buf = buf[len(checkedMagic):]
+	if len(buf) < 12 {
+		return nil, ErrChecksum
+	}
+
+	size := binary.BigEndian.Uint64(buf)
+	sum := binary.BigEndian.Uint32(buf[8:])
+	data := buf[12:]
+
+	if uint64(len(data)) != size || crc32.ChecksumIEEE(data) != sum {
+		return nil, ErrChecksum
+	}
+
+	return data, nil
+}
+
+//WriteChecked write data with checksum to file atomically, previous versions of file are kept as rotated backups,
+//they are rotated at most once per backupInterval
+func WriteChecked(filename string, data []byte) error {
+	buf := encodeChecked(data)
+
+	if _, err := os.Stat(filename); err == nil && backupDue(filename) {
+		if err := rotateBackups(filename); err != nil {
+			return err
+		}
+	}
+
+	return WriteFileAtomic(filename, buf, 0644)
+}
+
+//ReadChecked read data of file and verify its checksum, if file is damaged or missing then backups are tried.
+//File without checksum is accepted if legacy decodes it as file of previous version.
+//It return name of file from which data is read, error of file itself is returned only if neither file nor backups can be read.
+func ReadChecked(filename string, legacy func(data []byte) error) (data []byte, from string, err error) {
+	var firstErr error
+
+	for n := 0; n <= backupCount; n++ {
+		name := filename
+		if n > 0 {
+			name = backupName(filename, n)
+		}
+
+		buf, err := ioutil.ReadFile(name)
+		if err == nil {
+			if data, err = decodeChecked(buf, legacy); err == nil {
+				return data, name, nil
+			}
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, "", firstErr
+}
+
+//RemoveChecked remove file with its backups, missing files are not error
+func RemoveChecked(filename string) error {
+	for n := backupCount; n > 0; n-- {
+		if err := os.Remove(backupName(filename, n)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
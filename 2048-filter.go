@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+//leaderboardLines is count of results visible in leaderboard overlay at once, other results are scrolled
+const leaderboardLines = 10
+
+//Period is time range of results shown in leaderboard overlay
+type Period int
+
+const (
+	AllTime Period = iota
+	Today
+	ThisWeek
+)
+
+var periodNames = []string{"ALL TIME", "TODAY", "THIS WEEK"}
+
+func (p Period) String() string {
+	return periodNames[p]
+}
+
+//Start return beginning of period in local time, week starts on monday, all time has zero start
+func (p Period) Start(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch p {
+	case Today:
+		return day
+	case ThisWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return time.Time{}
+}
+
+//Player return player of result: its profile, or name typed by player in previous versions
+func (u User) Player() string {
+	if u.Profile != "" {
+		return u.Profile
+	}
+	return u.Name
+}
+
+//LeaderBoardFilter chooses results shown in leaderboard overlay
+type LeaderBoardFilter struct {
+	Period Period
+
+	//Player is player of shown results, empty player is all players
+	Player string
+
+	//Mode is mode of shown results, zero mode is all modes
+	Mode Mode
+
+	//BestPerPlayer keeps only the best result of every player in every mode
+	BestPerPlayer bool
+}
+
+//Filter return results matching filter from the best one
+func (lb LeaderBoard) Filter(f LeaderBoardFilter, now time.Time) (users []User) {
+	start := f.Period.Start(now)
+
+	type key struct {
+		player string
+		mode   Mode
+	}
+	best := make(map[key]int)
+
+	for _, u := range lb.Users {
+		if f.Mode != (Mode{}) && u.Mode() != f.Mode || f.Player != "" && u.Player() != f.Player || u.Date.Before(start) {
+			continue
+		}
+
+		if f.BestPerPlayer {
+			k := key{u.Player(), u.Mode()}
+			if i, ok := best[k]; ok {
+				if u.Score > users[i].Score {
+					users[i] = u
+				}
+				continue
+			}
+			best[k] = len(users)
+		}
+
+		users = append(users, u)
+	}
+
+	sort.Sort(sort.Reverse(LeaderBoard{users}))
+	return
+}
+
+//Players return sorted names of players which have results
+func (lb LeaderBoard) Players() (players []string) {
+	seen := make(map[string]bool)
+	for _, u := range lb.Users {
+		if p := u.Player(); !seen[p] {
+			seen[p] = true
+			players = append(players, p)
+		}
+	}
+
+	sort.Strings(players)
+	return
+}
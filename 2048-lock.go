@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+//lockFile take exclusive advisory lock of file, it waits until other process releases lock.
+//Lock is taken on separate file filename.lock, because file itself is replaced on every write.
+func lockFile(filename string) (unlock func(), err error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
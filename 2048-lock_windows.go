@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+//lockfileExclusiveLock is flag LOCKFILE_EXCLUSIVE_LOCK of LockFileEx
+const lockfileExclusiveLock = 2
+
+//lockFile take exclusive advisory lock of file, it waits until other process releases lock.
+//Lock is taken on separate file filename.lock, because file itself is replaced on every write.
+func lockFile(filename string) (unlock func(), err error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	ol := new(syscall.Overlapped)
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+		f.Close()
+	}, nil
+}
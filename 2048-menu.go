@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/sg3des/2048/engine"
+	"github.com/sg3des/fizzgui"
+)
+
+//Menu is overlay with options of new game
+type Menu struct {
+	Container *fizzgui.Container
+	wgtRows   *fizzgui.Widget
+	wgtCols   *fizzgui.Widget
+	wgtSpawn  *fizzgui.Widget
+	wgtTarget *fizzgui.Widget
+	wgtUndo   *fizzgui.Widget
+}
+
+//undoDepths is list of undo depths available in menu
+var undoDepths = []int{0, 1, 3, engine.DefaultUndoDepth, 50, 100}
+
+//NewMenu create new game menu, it is opened by click on 2048 in header
+func NewMenu() *Menu {
+	m := new(Menu)
+	m.Container = fizzgui.NewContainer("menu", "10%", "20%", "80%", "65%")
+	m.Container.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
+	m.Container.Zorder = 2
+	m.Container.Hidden = true
+
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	title := m.Container.NewText("New game")
+	title.Layout.SetWidth("100%")
+	title.TextAlign = fizzgui.TALIGN_CENTER
+	title.Style.TextColor = white
+
+	m.wgtRows = m.newStepper("Rows", func(_ *fizzgui.Widget) { m.step(&boardRows, -1) }, func(_ *fizzgui.Widget) { m.step(&boardRows, 1) })
+	m.wgtCols = m.newStepper("Cols", func(_ *fizzgui.Widget) { m.step(&boardCols, -1) }, func(_ *fizzgui.Widget) { m.step(&boardCols, 1) })
+	m.wgtSpawn = m.newStepper("Spawn", func(_ *fizzgui.Widget) { m.stepPolicy(-1) }, func(_ *fizzgui.Widget) { m.stepPolicy(1) })
+	m.wgtUndo = m.newStepper("Undo", func(_ *fizzgui.Widget) { m.stepUndo(-1) }, func(_ *fizzgui.Widget) { m.stepUndo(1) })
+	m.wgtTarget = m.newStepper("Target", func(_ *fizzgui.Widget) { m.stepTarget(target / 2) }, func(_ *fizzgui.Widget) { m.stepTarget(target * 2) })
+
+	start := m.Container.NewButton("START", NewGame)
+	start.Layout.SetX("4%")
+	start.Layout.SetWidth("28%")
+	start.Layout.SetHeight("50px")
+	start.Layout.PositionFixed = true
+	start.Layout.VAlign = fizzgui.VAlignBottom
+	start.Style.TextColor = white
+
+	saves := m.Container.NewButton("SAVES", func(_ *fizzgui.Widget) { slots.Toggle(nil) })
+	saves.Layout.SetX("36%")
+	saves.Layout.SetWidth("28%")
+	saves.Layout.SetHeight("50px")
+	saves.Layout.PositionFixed = true
+	saves.Layout.VAlign = fizzgui.VAlignBottom
+	saves.Style.TextColor = white
+
+	player := m.Container.NewButton("PLAYER", func(_ *fizzgui.Widget) { profiles.Show() })
+	player.Layout.SetX("68%")
+	player.Layout.SetWidth("28%")
+	player.Layout.SetHeight("50px")
+	player.Layout.PositionFixed = true
+	player.Layout.VAlign = fizzgui.VAlignBottom
+	player.Style.TextColor = white
+
+	m.Update()
+
+	return m
+}
+
+//newStepper create line with label, value and buttons for decrease and increase value, return value widget
+func (m *Menu) newStepper(label string, less, more func(*fizzgui.Widget)) *fizzgui.Widget {
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	wgtLabel := m.Container.NewText(label)
+	wgtLabel.Layout.SetWidth("30%")
+	wgtLabel.Font = TextFontSmall
+	wgtLabel.Style.TextColor = white
+
+	btnLess := m.Container.NewButton("<", less)
+	btnLess.Layout.SetWidth("15%")
+	btnLess.Style.TextColor = white
+
+	value := m.Container.NewText("")
+	value.Layout.SetWidth("40%")
+	value.TextAlign = fizzgui.TALIGN_CENTER
+	value.Style.TextColor = white
+
+	btnMore := m.Container.NewButton(">", more)
+	btnMore.Layout.SetWidth("15%")
+	btnMore.Style.TextColor = white
+
+	return value
+}
+
+//step change size of board for new game by delta in allowed limits
+func (m *Menu) step(size *int, delta int) {
+	if n := *size + delta; n >= engine.MinSize && n <= engine.MaxSize {
+		*size = n
+	}
+	m.Update()
+}
+
+//stepPolicy choose previous or next spawn policy for new game
+func (m *Menu) stepPolicy(delta int) {
+	names := engine.PolicyNames()
+	for i, name := range names {
+		if name == spawnPolicy {
+			spawnPolicy = names[(i+delta+len(names))%len(names)]
+			break
+		}
+	}
+	m.Update()
+}
+
+//stepTarget set winning tile of new game if it is valid
+func (m *Menu) stepTarget(n int) {
+	if engine.ValidTarget(n) {
+		target = n
+	}
+	m.Update()
+}
+
+//stepUndo choose previous or next undo depth from list, custom depth from flag is replaced by the nearest one
+func (m *Menu) stepUndo(delta int) {
+	i := 0
+	for i < len(undoDepths)-1 && undoDepths[i] < undoDepth {
+		i++
+	}
+
+	if i += delta; i >= 0 && i < len(undoDepths) {
+		undoDepth = undoDepths[i]
+	}
+	m.Update()
+}
+
+//Update refresh values of options
+func (m *Menu) Update() {
+	m.wgtRows.Text = strconv.Itoa(boardRows)
+	m.wgtCols.Text = strconv.Itoa(boardCols)
+	m.wgtSpawn.Text = spawnPolicy
+	m.wgtTarget.Text = strconv.Itoa(target)
+	m.wgtUndo.Text = strconv.Itoa(undoDepth)
+	if undoDepth == 0 {
+		m.wgtUndo.Text = "off"
+	}
+}
+
+//Toggle show or hide menu
+func (m *Menu) Toggle(_ *fizzgui.Widget) {
+	m.Container.Hidden = !m.Container.Hidden
+	m.Update()
+}
+
+func (m *Menu) Hide() {
+	m.Container.Hidden = true
+}
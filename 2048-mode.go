@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+//leaderboardTop is count of the best results kept in leaderboard of every mode, results with lower scores are dropped
+const leaderboardTop = 1000
+
+//Mode of game, results of different modes are ranked in separate leaderboards.
+//Target tile is variant of game.
+type Mode struct {
+	Rows   int
+	Cols   int
+	Policy string
+	Target int
+
+	//Undo is told by client, it can not be verified by replay
+	Undo bool
+}
+
+//NewMode return mode of game on board rows x cols with rules
+func NewMode(rows, cols int, r Rules) Mode {
+	return Mode{Rows: rows, Cols: cols, Policy: r.Policy, Target: r.Target, Undo: r.Undo > 0}
+}
+
+func (m Mode) String() string {
+	undo := "no undo"
+	if m.Undo {
+		undo = "undo"
+	}
+	return fmt.Sprintf("%s %s %d %s", sizeName(m.Rows, m.Cols), m.Policy, m.Target, undo)
+}
+
+//Mode return mode of game in which result is achieved
+func (u User) Mode() Mode {
+	return Mode{Rows: u.Rows, Cols: u.Cols, Policy: u.Rules.Policy, Target: u.Rules.Target, Undo: u.Rules.Undo > 0}
+}
+
+//Top return results of mode from the best one
+func (lb LeaderBoard) Top(m Mode) (users []User) {
+	for _, u := range lb.Users {
+		if u.Mode() == m {
+			users = append(users, u)
+		}
+	}
+
+	sort.Sort(sort.Reverse(LeaderBoard{users}))
+	return
+}
+
+//Best return the best result of mode
+func (lb LeaderBoard) Best(m Mode) User {
+	for _, u := range lb.Top(m) {
+		return u
+	}
+	return User{}
+}
+
+//Modes return modes which have results
+func (lb LeaderBoard) Modes() (modes []Mode) {
+	seen := make(map[Mode]bool)
+	for _, u := range lb.Users {
+		if m := u.Mode(); !seen[m] {
+			seen[m] = true
+			modes = append(modes, m)
+		}
+	}
+
+	sort.Slice(modes, func(i, j int) bool { return modes[i].String() < modes[j].String() })
+	return
+}
+
+//Trim keep only limit the best results of every mode
+func (lb *LeaderBoard) Trim(limit int) {
+	sort.Sort(sort.Reverse(*lb))
+
+	count := make(map[Mode]int)
+	users := lb.Users[:0]
+	for _, u := range lb.Users {
+		m := u.Mode()
+		if count[m] < limit {
+			count[m]++
+			users = append(users, u)
+		}
+	}
+	lb.Users = users
+}
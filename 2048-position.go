@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sg3des/2048/engine"
+)
+
+//positionsDir is directory of exported positions
+var positionsDir = "positions"
+
+//ReadPosition read position from JSON file and convert it to save, missing rules are taken from options of new game
+func ReadPosition(filename string) (*Save, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := engine.ReadPosition(f)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Save{
+		Magic:   saveMagic,
+		Version: saveVersion,
+		Time:    time.Now(),
+		Rows:    p.Rows,
+		Cols:    p.Cols,
+		Rules: Rules{
+			Policy: p.Policy,
+			Target: p.Target,
+			Undo:   undoDepth,
+		},
+		Game: TableState{
+			Cells: p.Cells(),
+			Score: p.Score,
+			Seed:  p.Seed,
+			Rand:  p.Rand,
+		},
+	}
+
+	if s.Rules.Policy == "" {
+		s.Rules.Policy = spawnPolicy
+	}
+	if s.Rules.Target == 0 {
+		s.Rules.Target = target
+	}
+	if s.Game.Seed == 0 {
+		s.Game.Seed = engine.NewSeed()
+	}
+	if s.Game.Rand == 0 {
+		s.Game.Rand = uint64(s.Game.Seed)
+	}
+
+	return s, s.Validate()
+}
+
+//ExportPosition write position of current game to new file in positions directory and return its name
+func ExportPosition() (string, error) {
+	f, err := createPositionFile(time.Now())
+	if err != nil {
+		return "", err
+	}
+	filename := f.Name()
+
+	err = engine.WritePosition(f, table.Position())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return filename, err
+}
+
+//createPositionFile create new file of position named by time, existing file is never overwritten,
+//so files exported within the same second get suffixes
+func createPositionFile(now time.Time) (*os.File, error) {
+	if err := os.MkdirAll(positionsDir, 0755); err != nil {
+		return nil, err
+	}
+
+	name := now.Format("position-20060102-150405")
+	for n := 1; ; n++ {
+		filename := filepath.Join(positionsDir, name+".json")
+		if n > 1 {
+			filename = filepath.Join(positionsDir, fmt.Sprintf("%s-%d.json", name, n))
+		}
+
+		f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
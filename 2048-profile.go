@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sg3des/fizzgui"
+)
+
+//profilesDir is directory of player profiles, every profile is subdirectory with its save, replay and statistics
+var profilesDir = "profiles"
+
+//profileFiles are files of profile, before profiles they were kept in data directory
+var profileFiles = []string{"2048.save", "2048.replay", "statistics"}
+
+//defaultProfile is name of profile created on first run, files of previous versions are moved to it
+const defaultProfile = "Player"
+
+//profilesPerPage is count of profiles shown in profiles overlay at once
+const profilesPerPage = 6
+
+//profile is name of current player profile
+var profile = defaultProfile
+
+//ListProfiles return names of profiles sorted by name
+func ListProfiles() ([]string, error) {
+	files, err := ioutil.ReadDir(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, fi := range files {
+		if fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+//CreateProfile create directory of new profile, name is cleaned to be used as file name, it return cleaned name
+func CreateProfile(name string) (string, error) {
+	name = SlotName(name)
+	if name == "" {
+		return "", fmt.Errorf("name of profile is empty")
+	}
+
+	dir := filepath.Join(profilesDir, name)
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("profile %s already exists", name)
+	}
+
+	return name, os.MkdirAll(dir, 0755)
+}
+
+//SetProfile point save, replay and statistics to files of profile
+func SetProfile(name string) {
+	profile = name
+
+	dir := filepath.Join(profilesDir, name)
+	saveFilename = filepath.Join(dir, "2048.save")
+	replayFilename = filepath.Join(dir, "2048.replay")
+	statsFilename = filepath.Join(dir, "statistics")
+}
+
+//SetupProfiles choose profile remembered in settings, or the first one if it does not exist anymore.
+//On first run default profile is created and save, replay and statistics of data directory are moved to it.
+func SetupProfiles(current string) (string, error) {
+	names, err := ListProfiles()
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if len(names) == 0 {
+		name, err := CreateProfile(defaultProfile)
+		if err != nil {
+			return "", err
+		}
+		moveFiles(dataDir, filepath.Join(profilesDir, name), withBackups(profileFiles...))
+		names = []string{name}
+	}
+
+	for _, name := range names {
+		if name == current {
+			SetProfile(name)
+			return name, nil
+		}
+	}
+
+	SetProfile(names[0])
+	return names[0], nil
+}
+
+//SwitchProfile make profile current: unfinished game of previous profile is saved,
+//then save and statistics of profile are loaded
+func SwitchProfile(name string) {
+	if name == profile {
+		return
+	}
+
+	playback.Stop()
+
+	if table != nil {
+		//result of lost game is already stored, unfinished game is kept in save of previous profile
+		if !table.lost {
+			if err := SaveGame(); err != nil {
+				log.Println("failed save game,", err)
+			}
+		}
+		if err := stats.Save(); err != nil {
+			log.Println("failed save statistics,", err)
+		}
+
+		table.Container.Close()
+		table = nil
+	}
+
+	SetProfile(name)
+	settings.Profile = name
+	if err := settings.Save(); err != nil {
+		log.Println("failed save settings,", err)
+	}
+
+	stats = LoadStats()
+	LoadGame()
+}
+
+//Profiles is overlay with list of player profiles, it is shown on start and opened from menu
+type Profiles struct {
+	Container *fizzgui.Container
+
+	Name  string
+	Names [profilesPerPage]*fizzgui.Widget
+
+	status   *fizzgui.Widget
+	profiles []string
+	page     int
+}
+
+//NewProfiles create overlay of profiles
+func NewProfiles() *Profiles {
+	p := new(Profiles)
+	p.Container = fizzgui.NewContainer("profiles", "5%", "10%", "90%", "85%")
+	p.Container.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
+	p.Container.Zorder = 3
+	p.Container.Hidden = true
+
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	title := p.Container.NewText("Who plays?")
+	title.Layout.SetWidth("100%")
+	title.TextAlign = fizzgui.TALIGN_CENTER
+	title.Style.TextColor = white
+
+	for i := 0; i < profilesPerPage; i++ {
+		i := i
+		p.Names[i] = p.Container.NewButton("", func(_ *fizzgui.Widget) { p.Choose(i) })
+		p.Names[i].Layout.SetWidth("100%")
+		p.Names[i].Style.TextColor = white
+		p.Names[i].Font = TextFontSmall
+	}
+
+	name := p.Container.NewText("New:")
+	name.Layout.SetWidth("20%")
+	name.Style.TextColor = white
+	name.Font = TextFontSmall
+
+	input := p.Container.NewInput("profilename", &p.Name, nil)
+	input.Layout.SetWidth("55%")
+	input.Style.TextColor = white
+	input.Font = TextFontSmall
+
+	create := p.Container.NewButton("CREATE", p.Create)
+	create.Layout.SetWidth("25%")
+	create.Style.TextColor = white
+	create.Font = TextFontSmall
+
+	p.status = p.Container.NewText("")
+	p.status.Layout.SetWidth("100%")
+	p.status.Style.TextColor = white
+	p.status.Font = TextFontSmall
+
+	prev := p.Container.NewButton("<", func(_ *fizzgui.Widget) { p.Page(-1) })
+	prev.Layout.SetWidth("20%")
+	prev.Layout.PositionFixed = true
+	prev.Layout.VAlign = fizzgui.VAlignBottom
+
+	closeBtn := p.Container.NewButton("Close", p.Hide)
+	closeBtn.Layout.SetX("25%")
+	closeBtn.Layout.SetWidth("50%")
+	closeBtn.Layout.PositionFixed = true
+	closeBtn.Layout.VAlign = fizzgui.VAlignBottom
+	closeBtn.Font = TextFontSmall
+
+	next := p.Container.NewButton(">", func(_ *fizzgui.Widget) { p.Page(1) })
+	next.Layout.SetX("80%")
+	next.Layout.SetWidth("20%")
+	next.Layout.PositionFixed = true
+	next.Layout.VAlign = fizzgui.VAlignBottom
+
+	return p
+}
+
+//Show overlay, page with current profile is shown
+func (p *Profiles) Show() {
+	menu.Hide()
+	p.Container.Hidden = false
+	p.status.Text = ""
+
+	names, _ := ListProfiles()
+	p.page = 0
+	for i, name := range names {
+		if name == profile {
+			p.page = i / profilesPerPage
+		}
+	}
+	p.Update()
+}
+
+func (p *Profiles) Hide(_ *fizzgui.Widget) {
+	p.Container.Hidden = true
+}
+
+//Update reload list of profiles and refresh current page, current profile is marked
+func (p *Profiles) Update() {
+	var err error
+	if p.profiles, err = ListProfiles(); err != nil {
+		p.status.Text = err.Error()
+	}
+
+	if p.page*profilesPerPage >= len(p.profiles) {
+		p.page = 0
+	}
+
+	for i := range p.Names {
+		name, ok := p.entry(i)
+		p.Names[i].Hidden = !ok
+		p.Names[i].Text = name
+		if name == profile {
+			p.Names[i].Text = "> " + name + " <"
+		}
+	}
+}
+
+//entry return name of profile shown in i-th line of current page
+func (p *Profiles) entry(i int) (string, bool) {
+	i += p.page * profilesPerPage
+	if i >= len(p.profiles) {
+		return "", false
+	}
+	return p.profiles[i], true
+}
+
+//Page switch to previous or next page of list
+func (p *Profiles) Page(delta int) {
+	if n := p.page + delta; n >= 0 && n*profilesPerPage < len(p.profiles) {
+		p.page = n
+	}
+	p.Update()
+}
+
+//Choose profile shown in i-th line and close overlay
+func (p *Profiles) Choose(i int) {
+	name, ok := p.entry(i)
+	if !ok {
+		return
+	}
+
+	p.Hide(nil)
+	SwitchProfile(name)
+}
+
+//Create profile named by input and switch to it
+func (p *Profiles) Create(_ *fizzgui.Widget) {
+	name, err := CreateProfile(p.Name)
+	if err != nil {
+		p.status.Text = err.Error()
+		return
+	}
+
+	p.Name = ""
+	p.Hide(nil)
+	SwitchProfile(name)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+//queueFilename is file of results submitted to leaderboard server
+var queueFilename = "submissions"
+
+//Statuses of submission
+const (
+	Pending  = "pending"
+	Accepted = "accepted"
+	Rejected = "rejected"
+)
+
+//Delays between attempts of submission, delay is doubled after every failed attempt
+const (
+	minBackoff = 30 * time.Second
+	maxBackoff = 24 * time.Hour
+)
+
+//queueInterval is period of checking of pending submissions
+const queueInterval = 15 * time.Second
+
+//queueHistory limits count of kept accepted and rejected submissions
+const queueHistory = 100
+
+//Submission is result sent to leaderboard server
+type Submission struct {
+	User User
+	URL  string
+
+	Status string
+	Rank   int
+	Error  string
+
+	Attempts int
+	NextTry  time.Time
+}
+
+//Queue keeps submissions in file until server accepts or rejects them, so results of games
+//finished while server is unreachable are sent on later launches
+type Queue struct {
+	mu       sync.Mutex
+	filename string
+	busy     bool
+	changed  bool
+
+	Items []*Submission
+
+	//submit sends result to server, it is replaced in tests
+	submit func(url string, u User) (*SubmitResponse, error)
+}
+
+//LoadQueue read queue from file
+func LoadQueue(filename string) *Queue {
+	q := &Queue{filename: filename, changed: true, submit: SubmitResult}
+	q.reload()
+	return q
+}
+
+//readQueue read submissions from file, missing file is empty queue
+func readQueue(filename string) (items []*Submission, err error) {
+	data, _, err := ReadChecked(filename, nil)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&items)
+	}
+	return
+}
+
+//reload merge submissions of file into queue, they may be changed by other instance of game,
+//it should be called with locked mutex
+func (q *Queue) reload() {
+	unlock, err := lockFile(q.filename)
+	if err != nil {
+		log.Println("failed lock queue of submissions,", err)
+		return
+	}
+	defer unlock()
+
+	items, err := readQueue(q.filename)
+	if err != nil {
+		log.Println("failed read queue of submissions,", err)
+	}
+	q.merge(items)
+}
+
+//save write queue to file, submissions added to file by other instance of game meanwhile are merged
+//and kept, file is locked from reading to writing. It should be called with locked mutex
+func (q *Queue) save() {
+	q.changed = true
+
+	unlock, err := lockFile(q.filename)
+	if err != nil {
+		log.Println("failed lock queue of submissions,", err)
+		return
+	}
+	defer unlock()
+
+	items, err := readQueue(q.filename)
+	if err != nil {
+		log.Println("failed read queue of submissions,", err)
+	}
+	q.merge(items)
+	q.trim()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(q.Items); err != nil {
+		log.Println("failed encode queue of submissions,", err)
+		return
+	}
+
+	if err := WriteChecked(q.filename, buf.Bytes()); err != nil {
+		log.Println("failed save queue of submissions,", err)
+	}
+}
+
+//find return submission of the same result to the same server, or nil
+func (q *Queue) find(s *Submission) *Submission {
+	for _, e := range q.Items {
+		if e.URL == s.URL && bytes.Equal(e.User.Replay, s.User.Replay) {
+			return e
+		}
+	}
+	return nil
+}
+
+//merge add submissions which are missing in queue, submission kept in both is taken from the one which is further:
+//finished submission is further than pending one, pending one with more attempts is further
+func (q *Queue) merge(items []*Submission) {
+	for _, s := range items {
+		e := q.find(s)
+		switch {
+		case e == nil:
+			q.Items = append(q.Items, s)
+			q.changed = true
+		case e.Status == Pending && (s.Status != Pending || s.Attempts > e.Attempts):
+			*e = *s
+			q.changed = true
+		}
+	}
+}
+
+//Add put result to queue and try to send it in background
+func (q *Queue) Add(url string, u User) {
+	q.mu.Lock()
+	q.Items = append(q.Items, &Submission{User: u, URL: url, Status: Pending})
+	q.save()
+	q.mu.Unlock()
+
+	go q.Process(time.Now())
+}
+
+//Process send pending submissions which attempt time has come
+func (q *Queue) Process(now time.Time) {
+	q.mu.Lock()
+	if q.busy {
+		q.mu.Unlock()
+		return
+	}
+	q.busy = true
+
+	//other instance of game may have sent submissions already
+	q.reload()
+
+	var due []*Submission
+	var sent []Submission
+	for _, s := range q.Items {
+		if s.Status == Pending && !now.Before(s.NextTry) {
+			due = append(due, s)
+			sent = append(sent, *s)
+		}
+	}
+	q.mu.Unlock()
+
+	type result struct {
+		resp *SubmitResponse
+		err  error
+	}
+	results := make([]result, len(due))
+	for i, s := range sent {
+		results[i].resp, results[i].err = q.submit(s.URL, s.User)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.busy = false
+
+	for i, s := range due {
+		if s.Status != Pending {
+			//submission is finished by other instance of game meanwhile
+			continue
+		}
+
+		resp, err := results[i].resp, results[i].err
+		s.Attempts++
+
+		switch {
+		case err == nil:
+			s.Status, s.Rank, s.Error = Accepted, resp.Rank, ""
+		case resp != nil && resp.Duplicate:
+			//server got result earlier: from other instance of game, or from attempt which response was lost
+			s.Status, s.Error = Accepted, ""
+		case resp != nil:
+			//server refused result, so it will not be accepted on retry
+			s.Status, s.Error = Rejected, resp.Error
+			log.Printf("result %d is rejected by %s, %s", s.User.Score, s.URL, resp.Error)
+		default:
+			s.Error = err.Error()
+			s.NextTry = now.Add(backoff(s.Attempts))
+			log.Printf("failed submit result %d to %s, next attempt at %s, %s", s.User.Score, s.URL, s.NextTry.Format("15:04:05"), err)
+		}
+	}
+
+	if len(due) > 0 {
+		q.save()
+	}
+}
+
+//backoff return delay after attempts failed attempts
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+//trim remove the oldest finished submissions over queueHistory
+func (q *Queue) trim() {
+	finished := 0
+	for _, s := range q.Items {
+		if s.Status != Pending {
+			finished++
+		}
+	}
+
+	items := q.Items[:0]
+	for _, s := range q.Items {
+		if s.Status != Pending && finished > queueHistory {
+			finished--
+			continue
+		}
+		items = append(items, s)
+	}
+	q.Items = items
+}
+
+//Run process queue periodically, it should be started in goroutine
+func (q *Queue) Run() {
+	for {
+		q.Process(time.Now())
+		time.Sleep(queueInterval)
+	}
+}
+
+//Counts return count of pending and accepted submissions
+func (q *Queue) Counts() (pending, accepted int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, s := range q.Items {
+		switch s.Status {
+		case Pending:
+			pending++
+		case Accepted:
+			accepted++
+		}
+	}
+	return
+}
+
+//Changed return true once after every change of queue
+func (q *Queue) Changed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	changed := q.changed
+	q.changed = false
+	return changed
+}
+
+//Status describe state of submission of result, it is empty if result was not submitted
+func (q *Queue) Status(u User) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := len(q.Items) - 1; i >= 0; i-- {
+		s := q.Items[i]
+		if !bytes.Equal(s.User.Replay, u.Replay) {
+			continue
+		}
+
+		switch s.Status {
+		case Accepted:
+			if s.Rank == 0 {
+				return Accepted
+			}
+			return fmt.Sprintf("accepted, rank %d", s.Rank)
+		case Rejected:
+			return "rejected: " + s.Error
+		}
+		return Pending
+	}
+	return ""
+}
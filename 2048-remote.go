@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+//settingsFilename is JSON file with settings of game in config directory
+var settingsFilename = "settings.json"
+
+//submitTimeout limits time of submission to leaderboard server
+const submitTimeout = 10 * time.Second
+
+//Settings of game which are kept between launches
+type Settings struct {
+	//LeaderboardURL is address of shared leaderboard server, results are submitted to it when game ends
+	LeaderboardURL string `json:"leaderboard_url,omitempty"`
+
+	//Profile is name of player profile chosen last time
+	Profile string `json:"profile,omitempty"`
+}
+
+//LoadSettings read settings, missing file gives default settings
+func LoadSettings() (s Settings) {
+	data, err := ioutil.ReadFile(settingsFilename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("failed read settings,", err)
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		log.Printf("failed parse settings %s, %s", settingsFilename, err)
+	}
+	return
+}
+
+//Save write settings to file
+func (s Settings) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(settingsFilename, append(data, '\n'), 0644)
+}
+
+//SubmitResult send result with replay to leaderboard server. Response is returned with error
+//only if server refused result, error without response means that submission may be retried.
+func SubmitResult(url string, u User) (*SubmitResponse, error) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: submitTimeout}
+	resp, err := client.Post(strings.TrimSuffix(url, "/")+"/api/submit", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r := new(SubmitResponse)
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return nil, fmt.Errorf("unexpected response %s, %s", resp.Status, err)
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("server failed %s: %s", resp.Status, r.Error)
+	}
+
+	if !r.Accepted {
+		return r, fmt.Errorf("result is rejected by server: %s", r.Error)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/sg3des/2048/engine"
+	"github.com/sg3des/fizzgui"
+)
+
+//replayFilename is replay of current game, it is written next to save
+var replayFilename = "2048.replay"
+
+//playbackSpeeds is list of speeds of playback, 1 is one move per playbackDelay
+var playbackSpeeds = []float32{0.25, 0.5, 1, 2, 4, 8, 16}
+
+//playbackDelay is pause between moves on normal speed in seconds
+const playbackDelay = 0.5
+
+//SaveReplay write replay of current game
+func SaveReplay() error {
+	data, err := table.Replay.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return WriteChecked(replayFilename, data)
+}
+
+//legacyReplay accept replay written without checksum
+func legacyReplay(data []byte) error {
+	return new(engine.Replay).UnmarshalBinary(data)
+}
+
+//ReadReplay read replay from file
+func ReadReplay(filename string) (*engine.Replay, error) {
+	data, _, err := ReadChecked(filename, legacyReplay)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(engine.Replay)
+	return r, r.UnmarshalBinary(data)
+}
+
+//loadReplay restore replay of current game from file, if replay does not lead to current board then it is ignored
+func loadReplay() {
+	data, _, err := ReadChecked(replayFilename, legacyReplay)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("failed read replay,", err)
+		}
+		return
+	}
+
+	r, err := matchReplay(data)
+	if err != nil {
+		log.Println("replay does not match saved game,", err)
+		return
+	}
+
+	table.Replay = r
+}
+
+//matchReplay decode replay and check that it leads to current board
+func matchReplay(data []byte) (*engine.Replay, error) {
+	r := new(engine.Replay)
+	if err := r.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	b, err := r.Final()
+	if err != nil {
+		return nil, err
+	}
+
+	if fmt.Sprint(b.Cells()) != fmt.Sprint(table.Cells()) || b.Score != table.Score || b.RandState() != table.RandState() {
+		return nil, fmt.Errorf("replay leads to other board")
+	}
+
+	return r, nil
+}
+
+//VerifyFile verify replay file and print its result, it return exit code
+func VerifyFile(filename string) int {
+	r, err := ReadReplay(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed read replay %s, %s\n", filename, err)
+		return 1
+	}
+
+	b, err := engine.Verify(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay %s is not valid: %s\n", filename, err)
+		return 1
+	}
+
+	fmt.Printf("replay %s is valid: board %s, seed %d, %d moves, score %d, max tile %d, won %v\n",
+		filename, sizeName(b.Rows, b.Cols), b.Seed, len(r.Steps), b.Score, b.MaxTile(), b.Won())
+	b.Dump(os.Stdout)
+	return 0
+}
+
+//Playback shows recorded game move by move, it replaces table until playback is stopped
+type Playback struct {
+	Container *fizzgui.Container
+	wgtInfo   *fizzgui.Widget
+	wgtPause  *fizzgui.Widget
+
+	replay *engine.Replay
+	game   *Table
+
+	step   int
+	speed  int
+	wait   float32
+	paused bool
+	err    error
+}
+
+//NewPlayback create panel of playback, it covers header while replay is shown
+func NewPlayback() *Playback {
+	p := &Playback{speed: 2}
+
+	p.Container = fizzgui.NewContainer("playback", "0", "0", "100%", "100")
+	p.Container.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
+	p.Container.Zorder = 3
+	p.Container.Hidden = true
+
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	p.wgtInfo = p.Container.NewText("")
+	p.wgtInfo.Layout.SetWidth("100%")
+	p.wgtInfo.TextAlign = fizzgui.TALIGN_CENTER
+	p.wgtInfo.Style.TextColor = white
+	p.wgtInfo.Font = TextFontSmall
+
+	slower := p.Container.NewButton("-", func(_ *fizzgui.Widget) { p.Speed(-1) })
+	slower.Layout.SetWidth("15%")
+	slower.Style.TextColor = white
+
+	p.wgtPause = p.Container.NewButton("PAUSE", func(_ *fizzgui.Widget) { p.Pause() })
+	p.wgtPause.Layout.SetWidth("35%")
+	p.wgtPause.Style.TextColor = white
+	p.wgtPause.Font = TextFontSmall
+
+	faster := p.Container.NewButton("+", func(_ *fizzgui.Widget) { p.Speed(1) })
+	faster.Layout.SetWidth("15%")
+	faster.Style.TextColor = white
+
+	stop := p.Container.NewButton("STOP", func(_ *fizzgui.Widget) { p.Stop() })
+	stop.Layout.SetWidth("35%")
+	stop.Style.TextColor = white
+	stop.Font = TextFontSmall
+
+	return p
+}
+
+//Active return true while replay is shown
+func (p *Playback) Active() bool {
+	return p.replay != nil
+}
+
+//Start show replay instead of current game
+func (p *Playback) Start(r *engine.Replay) error {
+	b, err := r.Board()
+	if err != nil {
+		return err
+	}
+
+	if p.Active() {
+		p.Stop()
+	}
+
+	p.replay, p.game = r, table
+	p.step, p.wait, p.paused, p.err = 0, playbackDelay, false, nil
+
+	endgame.Hide()
+	victory.Hide()
+	menu.Hide()
+	slots.Hide(nil)
+	if p.game != nil {
+		p.game.Container.Hidden = true
+	}
+
+	table = NewTable(r.Rows, r.Cols, r.Seed)
+	table.Board = b
+	table.Redraw()
+	for i, n := range b.Cells() {
+		if n != 0 {
+			table.appear(i)
+		}
+	}
+
+	p.Container.Hidden = false
+	p.Update(0)
+
+	return nil
+}
+
+//Stop playback and return to current game
+func (p *Playback) Stop() {
+	if !p.Active() {
+		return
+	}
+
+	p.Container.Hidden = true
+	p.replay = nil
+
+	table.Container.Close()
+	table = p.game
+	p.game = nil
+	if table == nil {
+		NewGame(nil)
+		return
+	}
+
+	w, h := windowSize(table.Rows, table.Cols)
+	window.SetSize(w, h)
+	window.SetTitle(fmt.Sprintf("2048 - seed %d", table.Seed))
+	table.Container.Hidden = false
+
+	if table.lost {
+		endgame.Show()
+	} else if table.Won() && !table.keepPlaying {
+		victory.Show()
+	}
+}
+
+//Pause or resume playback
+func (p *Playback) Pause() {
+	p.paused = !p.paused
+}
+
+//Speed choose slower or faster speed of playback
+func (p *Playback) Speed(delta int) {
+	if i := p.speed + delta; i >= 0 && i < len(playbackSpeeds) {
+		p.speed = i
+	}
+}
+
+//TimeScale return speed of animations, during playback it follows speed of playback
+func (p *Playback) TimeScale() float32 {
+	if !p.Active() {
+		return 1
+	}
+	return playbackSpeeds[p.speed]
+}
+
+//Update play next move when previous one is shown long enough, it is called every frame
+func (p *Playback) Update(dt float32) {
+	if !p.Active() {
+		return
+	}
+
+	if !p.paused && p.err == nil && p.step < len(p.replay.Steps) {
+		p.wait -= dt * p.TimeScale()
+		if p.wait <= 0 {
+			p.wait = playbackDelay
+
+			var r *engine.MoveResult
+			r, p.err = p.replay.Apply(table.Board, p.step)
+			table.Animate(r)
+			table.Redraw()
+			p.step++
+		}
+	}
+
+	p.wgtPause.Text = "PAUSE"
+	if p.paused {
+		p.wgtPause.Text = "PLAY"
+	}
+
+	switch {
+	case p.err != nil:
+		p.wgtInfo.Text = p.err.Error()
+	case p.step == len(p.replay.Steps):
+		p.wgtInfo.Text = fmt.Sprintf("Replay finished, %d moves, score %d", p.step, table.Score)
+	default:
+		p.wgtInfo.Text = fmt.Sprintf("Replay %d/%d  score %d  x%g", p.step, len(p.replay.Steps), table.Score, playbackSpeeds[p.speed])
+	}
+}
+
+//Key handle keys during playback: Space pause, Minus and Equal change speed, Escape stop
+func (p *Playback) Key(key glfw.Key) {
+	switch key {
+	case glfw.KeySpace:
+		p.Pause()
+	case glfw.KeyMinus:
+		p.Speed(-1)
+	case glfw.KeyEqual:
+		p.Speed(1)
+	case glfw.KeyEscape:
+		p.Stop()
+	}
+}
+
+//Review show replay of current game, it is called from game over overlay
+func (p *Playback) Review(_ *fizzgui.Widget) {
+	if table == nil || table.Replay == nil {
+		return
+	}
+
+	if err := p.Start(table.Replay); err != nil {
+		notice.Show(fmt.Sprintf("Replay can not be shown: %s", err))
+	}
+}
+
+//PlayFile show replay from file
+func (p *Playback) PlayFile(filename string) error {
+	r, err := ReadReplay(filename)
+	if err != nil {
+		return err
+	}
+
+	return p.Start(r)
+}
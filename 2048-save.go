@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sg3des/2048/engine"
+)
+
+//saveMagic identifies save file, saves without it are made before format became versioned
+const saveMagic = "2048save"
+
+//saveVersion is current version of save format, it should be increased on every incompatible change
+//of Save and migration from previous version should be added to migrations
+const saveVersion = 1
+
+var saveFilename = "2048.save"
+
+//Save is envelope of saved game
+type Save struct {
+	Magic   string
+	Version int
+	Time    time.Time
+
+	Rows  int
+	Cols  int
+	Rules Rules
+
+	Game TableState
+}
+
+//Rules of game, they are chosen in menu before new game
+type Rules struct {
+	Policy string `json:"policy"`
+	Target int    `json:"target"`
+	Undo   int    `json:"undo"`
+}
+
+//TableState is state of game on table
+type TableState struct {
+	Cells []int
+	Score int
+
+	//Seed is initial seed of game, Rand is current state of random numbers
+	Seed int64
+	Rand uint64
+
+	//KeepPlaying is true if player continued game after victory
+	KeepPlaying bool
+
+	//History is undo and redo stacks of game
+	History *engine.History
+
+	//Replay is encoded replay which leads to state, it is empty in saves of previous versions
+	Replay []byte
+
+	//Duration is time spent on game, Undone is true if undo was used in game
+	Duration time.Duration
+	Undone   bool
+
+	//Finished is true when result of game is stored, game may be continued by undo after game over
+	Finished bool
+}
+
+//saveHeader is beginning of Save, it is decoded first to determine version of format
+type saveHeader struct {
+	Magic   string
+	Version int
+}
+
+//migrations decode saves of previous versions and upgrade them to current version
+var migrations = map[int]func(data []byte) (*Save, error){
+	0: migrateV0,
+}
+
+//saveV0 is bare table state written before save format became versioned
+type saveV0 struct {
+	Rows  int
+	Cols  int
+	Cells []int
+	Score int
+
+	Seed int64
+	Rand uint64
+
+	Policy string
+
+	Target      int
+	KeepPlaying bool
+
+	History *engine.History
+
+	//Size contains size of square board of saves made before rectangular boards
+	Size int
+	//Items contains cells of saves made before board size became configurable, always 4x4
+	Items [16]int
+}
+
+//migrateV0 upgrade bare table state to versioned save
+func migrateV0(data []byte) (*Save, error) {
+	var old saveV0
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&old); err != nil {
+		return nil, err
+	}
+
+	if old.Rows == 0 {
+		if old.Size == 0 {
+			old.Size = 4
+			old.Cells = old.Items[:]
+		}
+		old.Rows, old.Cols = old.Size, old.Size
+	}
+
+	//saves made before seeds were stored continue with random seed
+	if old.Seed == 0 {
+		old.Seed = engine.NewSeed()
+		old.Rand = uint64(old.Seed)
+	}
+
+	//saves made before spawn policies were introduced used 50/50 spawns
+	if old.Policy == "" {
+		old.Policy = engine.Even
+	}
+
+	if old.Target == 0 {
+		old.Target = engine.DefaultTarget
+	}
+
+	if old.History == nil {
+		old.History = engine.NewHistory(engine.DefaultUndoDepth)
+	}
+
+	return &Save{
+		Magic:   saveMagic,
+		Version: saveVersion,
+		Rows:    old.Rows,
+		Cols:    old.Cols,
+		Rules: Rules{
+			Policy: old.Policy,
+			Target: old.Target,
+			Undo:   old.History.Depth,
+		},
+		Game: TableState{
+			Cells:       old.Cells,
+			Score:       old.Score,
+			Seed:        old.Seed,
+			Rand:        old.Rand,
+			KeepPlaying: old.KeepPlaying,
+			History:     old.History,
+		},
+	}, nil
+}
+
+//DecodeSave decode save of any known version and upgrade it to current version
+func DecodeSave(data []byte) (*Save, error) {
+	var h saveHeader
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&h); err != nil || h.Magic != saveMagic {
+		h.Version = 0
+	}
+
+	var s *Save
+	switch {
+	case h.Version == saveVersion:
+		s = new(Save)
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(s); err != nil {
+			return nil, err
+		}
+
+	case h.Version > saveVersion:
+		return nil, fmt.Errorf("save has version %d, but this version of game supports versions up to %d", h.Version, saveVersion)
+
+	default:
+		migrate, ok := migrations[h.Version]
+		if !ok {
+			return nil, fmt.Errorf("save has unsupported version %d", h.Version)
+		}
+
+		var err error
+		if s, err = migrate(data); err != nil {
+			return nil, fmt.Errorf("failed upgrade save from version %d, %s", h.Version, err)
+		}
+	}
+
+	return s, s.Validate()
+}
+
+//Validate check that save may be restored
+func (s *Save) Validate() error {
+	if !engine.ValidSize(s.Rows, s.Cols) {
+		return fmt.Errorf("invalid board %s", sizeName(s.Rows, s.Cols))
+	}
+
+	if len(s.Game.Cells) != s.Rows*s.Cols {
+		return fmt.Errorf("board %s contains %d cells", sizeName(s.Rows, s.Cols), len(s.Game.Cells))
+	}
+
+	if _, ok := engine.Policy(s.Rules.Policy); !ok {
+		return fmt.Errorf("unknown spawn policy %q", s.Rules.Policy)
+	}
+
+	if !engine.ValidTarget(s.Rules.Target) {
+		return fmt.Errorf("invalid target %d", s.Rules.Target)
+	}
+
+	if s.Game.History == nil {
+		s.Game.History = engine.NewHistory(s.Rules.Undo)
+	}
+
+	return nil
+}
+
+//EncodeSave encode save
+func EncodeSave(s *Save) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(s)
+	return buf.Bytes(), err
+}
+
+//NewSave create save of current state of table
+func NewSave(t *Table) *Save {
+	return &Save{
+		Magic:   saveMagic,
+		Version: saveVersion,
+		Time:    time.Now(),
+		Rows:    t.Rows,
+		Cols:    t.Cols,
+		Rules: Rules{
+			Policy: t.Policy.Name(),
+			Target: t.Target,
+			Undo:   t.History.Depth,
+		},
+		Game: *t.TableState(),
+	}
+}
+
+//legacySave accept save of previous versions written without checksum, they cleared save by empty file
+func legacySave(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := DecodeSave(data)
+	return err
+}
+
+//LoadGame restore save state, damaged save is restored from backup,
+//if neither save nor backups can be loaded then save is kept aside and warning is shown
+func LoadGame() {
+	data, from, err := ReadChecked(saveFilename, legacySave)
+	if os.IsNotExist(err) || err == nil && len(data) == 0 {
+		NewGame(nil)
+		return
+	}
+
+	var s *Save
+	if err == nil {
+		s, err = DecodeSave(data)
+	}
+
+	if err != nil {
+		log.Println("failed load saved game,", err)
+
+		broken := saveFilename + ".broken"
+		if data, rerr := ioutil.ReadFile(saveFilename); rerr == nil {
+			if werr := ioutil.WriteFile(broken, data, 0644); werr != nil {
+				log.Println("failed keep broken save,", werr)
+			}
+		}
+
+		NewGame(nil)
+		notice.Show(fmt.Sprintf("Saved game can not be loaded: %s. It is kept in %s", err, broken))
+		return
+	}
+
+	if from != saveFilename {
+		notice.Show(fmt.Sprintf("Saved game is damaged, it is restored from backup %s", from))
+	}
+
+	RestoreGame(s)
+	loadReplay()
+}
+
+//RestoreGame replace current game by saved one, current game is not finished, so its result is not stored
+func RestoreGame(s *Save) {
+	if table != nil {
+		table.Container.Close()
+	}
+
+	header.NewGame(NewMode(s.Rows, s.Cols, s.Rules))
+	endgame.Hide()
+	victory.Hide()
+	menu.Hide()
+
+	policy, _ := engine.Policy(s.Rules.Policy)
+
+	table = NewTable(s.Rows, s.Cols, s.Game.Seed)
+	table.Policy = policy
+	table.Target = s.Rules.Target
+	table.History = s.Game.History
+	table.History.Depth = s.Rules.Undo
+	table.RestoreState(&s.Game)
+
+	var err error
+	if table.Replay, err = matchReplay(s.Game.Replay); err != nil {
+		table.Replay = engine.NewReplay(table.Board)
+	}
+
+	if table.lost = !table.CanMove(); table.lost {
+		//saved lost game is a copy of game which result is already stored
+		table.finished = true
+		endgame.Show()
+	} else if table.Won() && !table.keepPlaying {
+		victory.Show()
+	}
+}
+
+//ReplaceGame restore saved game instead of current one, current unfinished game is kept in new autosave slot,
+//so player may return to it
+func ReplaceGame(s *Save) {
+	if table != nil && table.Replay != nil && len(table.Replay.Steps) > 0 && !table.lost {
+		if _, err := Autosave(NewSave(table), time.Now()); err != nil {
+			log.Println("failed keep current game,", err)
+		}
+	}
+
+	RestoreGame(s)
+
+	if err := SaveGame(); err != nil {
+		log.Println("failed save game,", err)
+	}
+}
+
+//SaveGame write state to file
+func SaveGame() error {
+	if table == nil {
+		return errors.New("table is nil")
+	}
+
+	data, err := EncodeSave(NewSave(table))
+	if err != nil {
+		return err
+	}
+
+	if err := WriteChecked(saveFilename, data); err != nil {
+		return err
+	}
+
+	//statistics follow save, so moves of restored game are counted after crash
+	if err := stats.Save(); err != nil {
+		log.Println("failed save statistics,", err)
+	}
+
+	return SaveReplay()
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/gob"
 	"fmt"
 	"log"
@@ -8,13 +9,19 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/sg3des/2048/engine"
 	"github.com/sg3des/fizzgui"
 )
 
 var leaderboardFilename = "leaderboard"
 
+//leaderboardCheckInterval is how often leaderboard file is checked for changes made by other running games
+const leaderboardCheckInterval = time.Second
+
 //Header struct contains information of current score and best results
 type Header struct {
 	con2048 *fizzgui.Container
@@ -23,23 +30,120 @@ type Header struct {
 	conCurr      *fizzgui.Container
 	wgtCurrName  *fizzgui.Widget
 	wgtCurrScore *fizzgui.Widget
+	wgtSubmit    *fizzgui.Widget
 
 	conBest      *fizzgui.Container
 	wgtBestName  *fizzgui.Widget
 	wgtBestScore *fizzgui.Widget
+	wgtStats     *fizzgui.Widget
+
+	conLB    *fizzgui.Container
+	wgtTitle *fizzgui.Widget
+	Lines    [leaderboardLines]*fizzgui.Widget
+	Details  [leaderboardLines]*fizzgui.Widget
+
+	//sortBy is index of column in leaderboardColumns by which leaderboard overlay is sorted
+	sortBy  int
+	sortAsc bool
 
-	conLB  *fizzgui.Container
-	Names  [10]*fizzgui.Widget
-	Scores [10]*fizzgui.Widget
+	//widgets of filter of leaderboard overlay
+	wgtMode   *fizzgui.Widget
+	wgtPeriod *fizzgui.Widget
+	wgtPlayer *fizzgui.Widget
+	wgtBestOf *fizzgui.Widget
+	filter    LeaderBoardFilter
+
+	//scroll is index of the first visible result, results of current player are highlighted
+	scroll    int
+	lineColor mgl32.Vec4
+
+	//lbFile is state of leaderboard file when it was read or written by this game,
+	//it is compared with file every leaderboardCheckInterval to notice results of other running games
+	lbFile    os.FileInfo
+	lbChecked time.Time
 
 	curr User
-	best User
+	mode Mode
+
+	//best is personal best score of current profile in mode of current game
+	best int
+
 	LeaderBoard
 }
 
 type User struct {
-	Score int
-	Name  string
+	Score int    `json:"score"`
+	Name  string `json:"name"`
+	Rows  int    `json:"rows"`
+	Cols  int    `json:"cols"`
+
+	//Profile is player profile which achieved result, results of previous versions have only free text Name
+	Profile string `json:"profile,omitempty"`
+
+	//Won is true if target tile was reached in game
+	Won bool `json:"won"`
+
+	//Replay is encoded replay of game, result is accepted to leaderboard only if replay leads to it
+	Replay []byte `json:"replay,omitempty"`
+
+	//Date is time when game was finished, Duration is time spent on game
+	Date     time.Time     `json:"date"`
+	Duration time.Duration `json:"duration"`
+
+	//MaxTile, Moves, Seed and rules except undo depth are taken from replay on verification
+	MaxTile int   `json:"max_tile"`
+	Moves   int   `json:"moves"`
+	Seed    int64 `json:"seed"`
+	Rules   Rules `json:"rules"`
+
+	//Undone is true if undo was used in game
+	Undone bool `json:"undone"`
+}
+
+//Verify re-play replay of result by rules of engine and check its score, size and victory,
+//fields which can be calculated from replay are filled by it.
+//Undone moves are not kept in replay, so undo rule and its usage are told by client and only checked to agree.
+func (u *User) Verify() error {
+	r := new(engine.Replay)
+	if err := r.UnmarshalBinary(u.Replay); err != nil {
+		return err
+	}
+
+	b, err := engine.Verify(r)
+	if err != nil {
+		return err
+	}
+
+	if b.Score != u.Score {
+		return fmt.Errorf("score %d does not match replay score %d", u.Score, b.Score)
+	}
+
+	if b.Rows != u.Rows || b.Cols != u.Cols {
+		return fmt.Errorf("board %s does not match replay board %s", u.BoardSize(), sizeName(b.Rows, b.Cols))
+	}
+
+	if b.Won() != u.Won {
+		return fmt.Errorf("victory %v does not match replay", u.Won)
+	}
+
+	if u.Undone && u.Rules.Undo == 0 {
+		return fmt.Errorf("undo is used, but it is disabled by rules")
+	}
+
+	u.MaxTile = b.MaxTile()
+	u.Moves = len(r.Steps)
+	u.Seed = r.Seed
+	u.Rules.Policy, u.Rules.Target = r.Policy, r.Target
+
+	return nil
+}
+
+//BoardSize return size of board on which result is achieved
+func (u User) BoardSize() string {
+	if u.Rows == 0 {
+		return sizeName(engine.DefaultSize, engine.DefaultSize)
+	}
+	return sizeName(u.Rows, u.Cols)
 }
 
 func NewHeader() *Header {
@@ -51,7 +155,13 @@ func NewHeader() *Header {
 
 	s.con2048 = fizzgui.NewContainer("score", "1", "0", "33.3%", "100")
 	s.con2048.Style.BackgroundColor = fizzgui.Color(236, 196, 0, 255)
-	s.wgt2048 = s.newWdiget(s.con2048, "2048", "100%", NumsFont)
+	s.wgt2048 = s.con2048.NewButton("2048", menu.Toggle)
+	s.wgt2048.Layout.SetWidth("100%")
+	s.wgt2048.Layout.SetHeight("100%")
+	s.wgt2048.Font = NumsFont
+	s.wgt2048.Style = fizzgui.NewStyle(fizzgui.Color(255, 255, 255, 255), mgl32.Vec4{0, 0, 0, 0}, mgl32.Vec4{0, 0, 0, 0}, 0)
+	s.wgt2048.StyleHover = fizzgui.NewStyle(fizzgui.Color(255, 255, 255, 255), mgl32.Vec4{0, 0, 0, 0}, mgl32.Vec4{0, 0, 0, 0}, 0)
+	s.wgt2048.StyleActive = fizzgui.NewStyle(colGrey, mgl32.Vec4{0, 0, 0, 0}, mgl32.Vec4{0, 0, 0, 0}, 0)
 
 	s.conCurr = fizzgui.NewContainer("currScore", "33.3%", "0", "33.3%", "100")
 	s.conCurr.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
@@ -62,12 +172,18 @@ func NewHeader() *Header {
 	s.wgtCurrScore = s.newWdiget(s.conCurr, "0", "0", TextFont)
 	s.wgtCurrScore.Layout.Padding.T = 0
 	s.wgtCurrScore.Layout.Margin.T = 0
+	s.wgtCurrScore.Layout.Padding.B = 0
+	s.wgtCurrScore.Layout.Margin.B = 0
+	s.wgtSubmit = s.newWdiget(s.conCurr, "", "0", TextFontTiny)
+	s.wgtSubmit.Layout.Padding.T = 0
+	s.wgtSubmit.Layout.Margin.T = 0
+	s.wgtSubmit.Style.TextColor = colGrey
 
 	s.conBest = fizzgui.NewContainer("bestScore", "66.6%", "0", "33.3%", "100")
 	s.conBest.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
 
 	s.wgtBestName = s.conBest.NewButton("BEST", s.ShowLeaderBoard) //(s.conBest, "BEST", "50%", TextFontSmall)
-	s.wgtBestName.Layout.SetWidth("100%")
+	s.wgtBestName.Layout.SetWidth("50%")
 	s.wgtBestName.Layout.SetHeight("50%")
 	s.wgtBestName.Layout.Padding.B = 0
 	s.wgtBestName.Layout.Margin.B = 0
@@ -76,7 +192,17 @@ func NewHeader() *Header {
 	s.wgtBestName.StyleHover = fizzgui.NewStyle(colGrey.Add(mgl32.Vec4{0.1, 0.1, 0.1, 0.1}), mgl32.Vec4{0, 0, 0, 0}, mgl32.Vec4{0, 0, 0, 0}, 0)
 	s.wgtBestName.StyleActive = fizzgui.NewStyle(colGrey, mgl32.Vec4{0, 0, 0, 0}, mgl32.Vec4{0, 0, 0, 0}, 0)
 
-	s.wgtBestScore = s.newWdiget(s.conBest, strconv.Itoa(s.best.Score), "0", TextFont)
+	s.wgtStats = s.conBest.NewButton("STATS", statsScreen.Toggle)
+	s.wgtStats.Layout.SetWidth("50%")
+	s.wgtStats.Layout.SetHeight("50%")
+	s.wgtStats.Layout.Padding.B = 0
+	s.wgtStats.Layout.Margin.B = 0
+	s.wgtStats.Font = TextFontSmall
+	s.wgtStats.Style = s.wgtBestName.Style
+	s.wgtStats.StyleHover = s.wgtBestName.StyleHover
+	s.wgtStats.StyleActive = s.wgtBestName.StyleActive
+
+	s.wgtBestScore = s.newWdiget(s.conBest, strconv.Itoa(s.best), "0", TextFont)
 	s.wgtBestScore.Layout.Padding.T = 0
 	s.wgtBestScore.Layout.Margin.T = 0
 
@@ -111,6 +237,75 @@ func (lb LeaderBoard) Swap(i, j int) {
 	lb.Users[i], lb.Users[j] = lb.Users[j], lb.Users[i]
 }
 
+//Verified return results confirmed by their replays, other results are dropped.
+//Results are verified once when they are added to leaderboard, stored results are trusted.
+func (lb LeaderBoard) Verified() (users []User) {
+	for _, u := range lb.Users {
+		if err := u.Verify(); err != nil {
+			log.Printf("result %d of %s is dropped, %s", u.Score, u.Name, err)
+			continue
+		}
+		users = append(users, u)
+	}
+	return
+}
+
+//splitReplayed return results with replays and results of previous versions without them
+func (lb LeaderBoard) splitReplayed() (replayed, legacy []User) {
+	for _, u := range lb.Users {
+		if len(u.Replay) == 0 {
+			legacy = append(legacy, u)
+		} else {
+			replayed = append(replayed, u)
+		}
+	}
+	return
+}
+
+//unverifiedName return name of file which keeps results of leaderboard which can not be verified,
+//they are results of previous versions of game which did not record replays
+func unverifiedName(filename string) string {
+	return filename + ".unverified"
+}
+
+//keepUnverified move results without replays to file of unverified results of leaderboard filename,
+//so they are not lost when leaderboard is written. They are told apart by player, score and date.
+func (lb *LeaderBoard) keepUnverified(filename string) error {
+	replayed, users := lb.splitReplayed()
+	if len(users) == 0 {
+		return nil
+	}
+
+	name := unverifiedName(filename)
+	kept, err := readLeaderBoard(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	count := len(kept.Users)
+	for _, u := range users {
+		found := false
+		for _, e := range kept.Users {
+			if e.Player() == u.Player() && e.Score == u.Score && e.Date.Equal(u.Date) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept.Users = append(kept.Users, u)
+		}
+	}
+
+	if len(kept.Users) > count {
+		if err := kept.Write(name); err != nil {
+			return err
+		}
+	}
+
+	lb.Users = replayed
+	return nil
+}
+
 func (lb LeaderBoard) UsersList() string {
 	var list []string
 	for _, u := range lb.Users {
@@ -122,70 +317,410 @@ func (lb LeaderBoard) UsersList() string {
 }
 
 func (s *Header) loadLeaderBoard() {
-	s.conLB = fizzgui.NewContainer("leaderboard", "10%", "10%", "80%", "80%")
+	s.conLB = fizzgui.NewContainer("leaderboard", "2%", "2%", "96%", "96%")
 	s.conLB.Zorder = 3
 	s.conLB.Hidden = true
 
-	title := s.conLB.NewText("Leader Board")
-	title.TextAlign = fizzgui.TALIGN_CENTER
-	title.Layout.SetWidth("100%")
+	s.wgtTitle = s.conLB.NewText("Leader Board")
+	s.wgtTitle.TextAlign = fizzgui.TALIGN_CENTER
+	s.wgtTitle.Layout.SetWidth("100%")
+
+	prevMode := s.conLB.NewButton("<", func(_ *fizzgui.Widget) { s.StepMode(-1) })
+	prevMode.Layout.SetWidth("10%")
+	prevMode.Font = TextFontSmall
+
+	s.wgtMode = s.conLB.NewText("")
+	s.wgtMode.Layout.SetWidth("80%")
+	s.wgtMode.TextAlign = fizzgui.TALIGN_CENTER
+	s.wgtMode.Font = TextFontSmall
+
+	nextMode := s.conLB.NewButton(">", func(_ *fizzgui.Widget) { s.StepMode(1) })
+	nextMode.Layout.SetWidth("10%")
+	nextMode.Font = TextFontSmall
+
+	s.wgtPeriod = s.conLB.NewButton("", func(_ *fizzgui.Widget) { s.StepPeriod() })
+	s.wgtPeriod.Layout.SetWidth("30%")
+	s.wgtPeriod.Font = TextFontTiny
 
-	for i := 0; i < 10; i++ {
-		wgtName := s.conLB.NewText("")
-		wgtName.Font = TextFontSmall
-		wgtName.Layout.SetWidth("50%")
+	s.wgtPlayer = s.conLB.NewButton("", func(_ *fizzgui.Widget) { s.StepPlayer() })
+	s.wgtPlayer.Layout.SetWidth("35%")
+	s.wgtPlayer.Font = TextFontTiny
 
-		wgtScore := s.conLB.NewText("")
-		wgtScore.Font = TextFontSmall
-		wgtScore.Layout.SetWidth("45%")
-		wgtScore.TextAlign = fizzgui.TALIGN_RIGHT
+	s.wgtBestOf = s.conLB.NewButton("", func(_ *fizzgui.Widget) { s.ToggleBestPerPlayer() })
+	s.wgtBestOf.Layout.SetWidth("35%")
+	s.wgtBestOf.Font = TextFontTiny
 
-		s.Names[i] = wgtName
-		s.Scores[i] = wgtScore
+	for i, col := range leaderboardColumns {
+		i := i
+		btn := s.conLB.NewButton(col.Name, func(_ *fizzgui.Widget) { s.SortLeaderBoard(i) })
+		btn.Layout.SetWidth("20%")
+		btn.Font = TextFontTiny
 	}
 
+	for i := range s.Lines {
+		s.Lines[i] = s.conLB.NewText("")
+		s.Lines[i].Font = TextFontTiny
+		s.Lines[i].Layout.SetWidth("100%")
+		s.Lines[i].Layout.Padding.B = 0
+		s.Lines[i].Layout.Margin.B = 0
+
+		s.Details[i] = s.conLB.NewText("")
+		s.Details[i].Font = TextFontTiny
+		s.Details[i].Layout.SetWidth("100%")
+		s.Details[i].Layout.Padding.T = 0
+		s.Details[i].Layout.Margin.T = 0
+		s.Details[i].Style.TextColor = fizzgui.Color(120, 110, 100, 255)
+	}
+	s.lineColor = s.Lines[0].Style.TextColor
+
+	up := s.conLB.NewButton("^", func(_ *fizzgui.Widget) { s.Scroll(-leaderboardLines) })
+	up.Layout.SetWidth("20%")
+	up.Layout.PositionFixed = true
+	up.Layout.VAlign = fizzgui.VAlignBottom
+
 	closeBtn := s.conLB.NewButton("Close", s.CloseLeaderBoard)
+	closeBtn.Layout.SetX("25%")
 	closeBtn.Layout.SetWidth("50%")
 	closeBtn.Layout.PositionFixed = true
-	closeBtn.Layout.HAlign = fizzgui.HAlignCenter
 	closeBtn.Layout.VAlign = fizzgui.VAlignBottom
 	closeBtn.Font = TextFontSmall
 
-	f, err := os.Open(leaderboardFilename)
+	down := s.conLB.NewButton("v", func(_ *fizzgui.Widget) { s.Scroll(leaderboardLines) })
+	down.Layout.SetX("80%")
+	down.Layout.SetWidth("20%")
+	down.Layout.PositionFixed = true
+	down.Layout.VAlign = fizzgui.VAlignBottom
+
+	s.reloadLeaderBoard()
+}
+
+//ReadLeaderBoard read leaderboard file, results in it are verified when they are added, so they are not verified again.
+//Results of previous versions without replays are not ranked, so they are skipped.
+func ReadLeaderBoard(filename string) (lb LeaderBoard, err error) {
+	lb, err = readLeaderBoard(filename)
+	lb.Users, _ = lb.splitReplayed()
+	return
+}
+
+//legacyLeaderBoard accept leaderboard of previous versions written without checksum
+func legacyLeaderBoard(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(new(LeaderBoard))
+}
+
+//readLeaderBoard read all results of leaderboard file, also results without replays
+func readLeaderBoard(filename string) (lb LeaderBoard, err error) {
+	data, from, err := ReadChecked(filename, legacyLeaderBoard)
 	if err != nil {
 		return
 	}
+	if from != filename {
+		log.Printf("leaderboard is damaged, it is restored from backup %s", from)
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&lb)
+	return
+}
+
+//Write write leaderboard to file
+func (lb LeaderBoard) Write(filename string) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(lb); err != nil {
+		return err
+	}
+
+	return WriteChecked(filename, buf.Bytes())
+}
+
+//Contains return true if the same result is already in leaderboard, results are compared by their replays
+func (lb LeaderBoard) Contains(u User) bool {
+	for _, e := range lb.Users {
+		if bytes.Equal(e.Replay, u.Replay) {
+			return true
+		}
+	}
+	return false
+}
+
+//Merge add results of other leaderboard which are not yet in leaderboard
+func (lb *LeaderBoard) Merge(other LeaderBoard) {
+	for _, u := range other.Users {
+		if !lb.Contains(u) {
+			lb.Users = append(lb.Users, u)
+		}
+	}
+}
+
+//Update merge leaderboard with results in file and write it back, file is locked meanwhile,
+//so results written by other running games are not lost. It return state of written file.
+func (lb *LeaderBoard) Update(filename string, limit int) (os.FileInfo, error) {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stored, err := readLeaderBoard(filename)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("failed read leaderboard %s, it is overwritten, %s", filename, err)
+	}
+	if err := stored.keepUnverified(filename); err != nil {
+		return nil, err
+	}
+	lb.Merge(stored)
+	lb.Trim(limit)
+
+	if err := lb.Write(filename); err != nil {
+		return nil, err
+	}
+	return os.Stat(filename)
+}
+
+//loadLeaderBoardFile read leaderboard file under lock, it return state of read file
+func loadLeaderBoardFile(filename string) (LeaderBoard, os.FileInfo, error) {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return LeaderBoard{}, nil, err
+	}
+	defer unlock()
 
-	err = gob.NewDecoder(f).Decode(&s.LeaderBoard)
+	fi, err := os.Stat(filename)
 	if err != nil {
-		log.Println(err)
+		return LeaderBoard{}, nil, err
+	}
+
+	lb, err := ReadLeaderBoard(filename)
+	return lb, fi, err
+}
+
+//changedFile return true if file is changed since its previous state was taken
+func changedFile(prev, fi os.FileInfo) bool {
+	return prev == nil || !fi.ModTime().Equal(prev.ModTime()) || fi.Size() != prev.Size()
+}
+
+//CheckLeaderBoard reload leaderboard if its file is changed by other running game, it is called every frame
+func (s *Header) CheckLeaderBoard() {
+	if time.Since(s.lbChecked) < leaderboardCheckInterval {
 		return
 	}
+	s.lbChecked = time.Now()
 
-	sort.Sort(sort.Reverse(s.LeaderBoard))
+	fi, err := os.Stat(leaderboardFilename)
+	if err != nil || !changedFile(s.lbFile, fi) {
+		return
+	}
 
-	for _, u := range s.LeaderBoard.Users {
-		s.best = u
-		break
+	s.reloadLeaderBoard()
+	s.best = s.PersonalBest(s.mode)
+	s.UpdateBest()
+	if !s.conLB.Hidden {
+		s.updateLeaderBoard()
 	}
 }
 
+//reloadLeaderBoard replace results of leaderboard by results from file
+func (s *Header) reloadLeaderBoard() {
+	lb, fi, err := loadLeaderBoardFile(leaderboardFilename)
+	if fi != nil {
+		s.lbFile = fi
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("failed read leaderboard,", err)
+		}
+		return
+	}
+
+	lb.Trim(leaderboardTop)
+	s.LeaderBoard = lb
+}
+
+//leaderboardColumns is list of columns by which leaderboard overlay can be sorted, first column is default
+var leaderboardColumns = []struct {
+	Name string
+	Less func(a, b User) bool
+}{
+	{"SCORE", func(a, b User) bool { return a.Score < b.Score }},
+	{"TILE", func(a, b User) bool { return a.MaxTile < b.MaxTile }},
+	{"MOVES", func(a, b User) bool { return a.Moves < b.Moves }},
+	{"TIME", func(a, b User) bool { return a.Duration < b.Duration }},
+	{"DATE", func(a, b User) bool { return a.Date.Before(b.Date) }},
+}
+
 func (s *Header) ShowLeaderBoard(_ *fizzgui.Widget) {
 	if !s.conLB.Hidden {
 		s.conLB.Hidden = true
 		return
 	}
 	s.conLB.Hidden = false
+	s.filter.Mode = s.mode
+	s.scroll = 0
+	s.updateLeaderBoard()
+}
+
+//StepMode show results of previous or next mode, the first choice is all modes,
+//mode of current game is listed even without results
+func (s *Header) StepMode(delta int) {
+	modes := append([]Mode{{}}, s.LeaderBoard.Modes()...)
+	if len(s.LeaderBoard.Top(s.mode)) == 0 {
+		modes = append(modes, s.mode)
+	}
+
+	for j, m := range modes {
+		if m == s.filter.Mode {
+			s.filter.Mode = modes[(j+delta+len(modes))%len(modes)]
+			break
+		}
+	}
+	s.scroll = 0
+	s.updateLeaderBoard()
+}
+
+//StepPeriod show results of next time range
+func (s *Header) StepPeriod() {
+	s.filter.Period = (s.filter.Period + 1) % Period(len(periodNames))
+	s.scroll = 0
+	s.updateLeaderBoard()
+}
+
+//StepPlayer show results of next player, the first choice is all players,
+//current profile is listed even without results
+func (s *Header) StepPlayer() {
+	players := append([]string{""}, s.LeaderBoard.Players()...)
+	listed := false
+	for _, p := range players {
+		listed = listed || p == profile
+	}
+	if !listed {
+		players = append(players, profile)
+	}
+
+	for j, p := range players {
+		if p == s.filter.Player {
+			s.filter.Player = players[(j+1)%len(players)]
+			break
+		}
+	}
+	s.scroll = 0
+	s.updateLeaderBoard()
+}
+
+//ToggleBestPerPlayer switch between all results and the best result of every player
+func (s *Header) ToggleBestPerPlayer() {
+	s.filter.BestPerPlayer = !s.filter.BestPerPlayer
+	s.scroll = 0
+	s.updateLeaderBoard()
+}
+
+//Scroll move list of results by delta lines
+func (s *Header) Scroll(delta int) {
+	s.scroll += delta
+	s.updateLeaderBoard()
+}
+
+//KeyLeaderBoard handle keys while leaderboard overlay is open: arrows, PageUp, PageDown, Home and End scroll, Escape closes
+func (s *Header) KeyLeaderBoard(key glfw.Key) {
+	switch key {
+	case glfw.KeyUp:
+		s.Scroll(-1)
+	case glfw.KeyDown:
+		s.Scroll(1)
+	case glfw.KeyPageUp:
+		s.Scroll(-leaderboardLines)
+	case glfw.KeyPageDown:
+		s.Scroll(leaderboardLines)
+	case glfw.KeyHome:
+		s.Scroll(-len(s.LeaderBoard.Users))
+	case glfw.KeyEnd:
+		s.Scroll(len(s.LeaderBoard.Users))
+	case glfw.KeyEscape:
+		s.CloseLeaderBoard(nil)
+	}
+}
+
+//SortLeaderBoard sort leaderboard overlay by column, repeated choice of the same column reverse order
+func (s *Header) SortLeaderBoard(column int) {
+	if s.sortBy == column {
+		s.sortAsc = !s.sortAsc
+	} else {
+		s.sortBy, s.sortAsc = column, false
+	}
+	s.updateLeaderBoard()
+}
 
-	for i, u := range s.LeaderBoard.Users {
-		if i > 9 {
-			return
+//updateLeaderBoard fill visible lines of leaderboard overlay with filtered results sorted by chosen column
+func (s *Header) updateLeaderBoard() {
+	s.wgtMode.Text = "ALL MODES"
+	if s.filter.Mode != (Mode{}) {
+		s.wgtMode.Text = s.filter.Mode.String()
+	}
+	s.wgtPeriod.Text = s.filter.Period.String()
+	s.wgtPlayer.Text = "ALL PLAYERS"
+	if s.filter.Player != "" {
+		s.wgtPlayer.Text = s.filter.Player
+	}
+	s.wgtBestOf.Text = "ALL RESULTS"
+	if s.filter.BestPerPlayer {
+		s.wgtBestOf.Text = "BEST PER PLAYER"
+	}
+
+	users := s.LeaderBoard.Filter(s.filter, time.Now())
+	less := leaderboardColumns[s.sortBy].Less
+	sort.SliceStable(users, func(i, j int) bool {
+		if s.sortAsc {
+			return less(users[i], users[j])
+		}
+		return less(users[j], users[i])
+	})
+
+	if s.scroll > len(users)-leaderboardLines {
+		s.scroll = len(users) - leaderboardLines
+	}
+	if s.scroll < 0 {
+		s.scroll = 0
+	}
+
+	s.wgtTitle.Text = "Leader Board"
+	if len(users) > leaderboardLines {
+		s.wgtTitle.Text = fmt.Sprintf("Leader Board %d-%d of %d", s.scroll+1, s.scroll+leaderboardLines, len(users))
+	}
+
+	for i := range s.Lines {
+		s.Lines[i].Text, s.Details[i].Text = "", ""
+		s.Lines[i].Style.TextColor = s.lineColor
+
+		n := s.scroll + i
+		if n >= len(users) {
+			continue
+		}
+
+		u := users[n]
+		mark := " "
+		if u.Won {
+			mark = "*"
+		}
+		if u.Profile == profile {
+			s.Lines[i].Style.TextColor = fizzgui.Color(246, 93, 59, 255)
+		}
+
+		s.Lines[i].Text = fmt.Sprintf("%3d %s %-14s %7d %6d %5d %9s", n+1, mark, u.Player(), u.Score, u.MaxTile, u.Moves, u.Duration.Truncate(time.Second))
+		s.Details[i].Text = fmt.Sprintf("     %s %s to %d, %s, seed %d, %s", u.BoardSize(), u.Rules.Policy, u.Rules.Target, u.UndoName(), u.Seed, u.Date.Format("2006-01-02 15:04"))
+		if status := submissions.Status(u); status != "" {
+			s.Details[i].Text += ", " + status
 		}
-		s.Names[i].Text = fmt.Sprintf("%2d   %-20s", i+1, u.Name)
-		s.Scores[i].Text = fmt.Sprintf("%d", u.Score)
 	}
 }
 
+//UndoName describe undo rule of game and whether undo was used
+func (u User) UndoName() string {
+	switch {
+	case u.Rules.Undo == 0 && !u.Undone:
+		return "no undo"
+	case u.Undone:
+		return fmt.Sprintf("undo %d used", u.Rules.Undo)
+	}
+	return fmt.Sprintf("undo %d unused", u.Rules.Undo)
+}
+
 func (s *Header) CloseLeaderBoard(_ *fizzgui.Widget) {
 	s.conLB.Hidden = true
 }
@@ -195,42 +730,89 @@ func (s *Header) AddScore(score int) {
 	s.UpdateCurr()
 }
 
-func (s *Header) NewGame() {
-	if s.curr.Score > 0 {
-		s.writeLeaderBoard()
+//PersonalBest return the best score of current profile in mode, it is kept in statistics of profile
+//because the best results of other players may push it out of leaderboard
+func (s *Header) PersonalBest(m Mode) int {
+	best := stats.Best[m.Key()]
+	for _, u := range s.LeaderBoard.Top(m) {
+		if u.Profile == profile && u.Score > best {
+			best = u.Score
+		}
 	}
+	return best
+}
 
-	if s.curr.Score > s.best.Score {
-		s.best = s.curr
-		s.UpdateBest()
-	}
+//NewGame reset score for new game of mode, best score follows mode
+func (s *Header) NewGame(m Mode) {
+	s.mode = m
+	s.best = s.PersonalBest(m)
+	s.UpdateBest()
 
 	s.curr.Score = 0
+	s.curr.Rows, s.curr.Cols = m.Rows, m.Cols
+	s.curr.Won = false
 	s.UpdateCurr()
 }
 
-func (s *Header) writeLeaderBoard() {
-	s.LeaderBoard.Users = append(s.LeaderBoard.Users, s.curr)
-	sort.Sort(sort.Reverse(s.LeaderBoard))
-	if len(s.LeaderBoard.Users) > 9 {
-		s.LeaderBoard.Users = s.LeaderBoard.Users[:10]
+//writeLeaderBoard add verified result of current game to leaderboard and merge it with leaderboard file,
+//it return false if result is not accepted
+func (s *Header) writeLeaderBoard() bool {
+	u := s.curr
+	u.Name, u.Profile = profile, profile
+	u.Won = table.Won()
+	u.Date = time.Now()
+	u.Duration = table.Duration
+	u.Undone = table.Undone
+	u.Rules.Undo = table.History.Depth
+
+	var err error
+	if u.Replay, err = table.Replay.MarshalBinary(); err == nil {
+		err = u.Verify()
+	}
+	if err != nil {
+		log.Printf("result %d is not accepted to leaderboard, %s", u.Score, err)
+		return false
+	}
+	if s.LeaderBoard.Contains(u) {
+		return true
+	}
+
+	s.LeaderBoard.Users = append(s.LeaderBoard.Users, u)
+	stats.RecordBest(u)
+	if settings.LeaderboardURL != "" {
+		submissions.Add(settings.LeaderboardURL, u)
 	}
 
-	f, err := os.OpenFile(leaderboardFilename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	fi, err := s.LeaderBoard.Update(leaderboardFilename, leaderboardTop)
 	if err != nil {
-		log.Println("failed open %s for store result", leaderboardFilename)
+		s.LeaderBoard.Trim(leaderboardTop)
+		log.Printf("failed store result to %s, %s", leaderboardFilename, err)
+		return true
+	}
+	s.lbFile = fi
+
+	return true
+}
+
+//UpdateSubmissions show counts of pending and accepted submissions to leaderboard server when they change
+func (s *Header) UpdateSubmissions() {
+	if !submissions.Changed() {
 		return
 	}
-	defer f.Close()
 
-	err = gob.NewEncoder(f).Encode(s.LeaderBoard)
-	if err != nil {
-		log.Println("failed encode data,", err)
+	pending, accepted := submissions.Counts()
+	switch {
+	case pending > 0:
+		s.wgtSubmit.Text = fmt.Sprintf("%d pending, %d accepted", pending, accepted)
+	case accepted > 0:
+		s.wgtSubmit.Text = fmt.Sprintf("%d accepted", accepted)
+	default:
+		s.wgtSubmit.Text = ""
 	}
 }
 
 func (s *Header) UpdateBest() {
-	s.wgtBestScore.Text = strconv.Itoa(s.best.Score)
+	s.wgtBestScore.Text = strconv.Itoa(s.best)
 }
 
 func (s *Header) UpdateCurr() {
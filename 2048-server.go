@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ErrSubmitted is error of submission of result which server already has
+var ErrSubmitted = errors.New("result is already submitted")
+
+//maxSubmitSize limits size of submission with replay
+const maxSubmitSize = 4 << 20
+
+//Timeouts of connections to server, submission of the biggest replay should fit in serverTimeout on slow network
+const (
+	serverTimeout     = 30 * time.Second
+	serverIdleTimeout = 2 * time.Minute
+)
+
+//Server is shared leaderboard, it accepts results with replays, verifies them and serves ranked lists per mode
+type Server struct {
+	mu       sync.Mutex
+	filename string
+	top      int
+
+	LeaderBoard
+}
+
+//SubmitResponse is answer on submission of result
+type SubmitResponse struct {
+	Accepted bool   `json:"accepted"`
+	Rank     int    `json:"rank,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	//Duplicate is true if server already has result, it is answered with status 409
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+//ModeInfo describes mode of game on server
+type ModeInfo struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Results int    `json:"results"`
+}
+
+//Key return mode as string which can be used in URL
+func (m Mode) Key() string {
+	undo := "noundo"
+	if m.Undo {
+		undo = "undo"
+	}
+	return fmt.Sprintf("%s-%s-%d-%s", sizeName(m.Rows, m.Cols), m.Policy, m.Target, undo)
+}
+
+//ParseMode parse mode from string returned by Key
+func ParseMode(key string) (m Mode, err error) {
+	parts := strings.Split(key, "-")
+	if len(parts) != 4 || parts[3] != "undo" && parts[3] != "noundo" {
+		return m, fmt.Errorf("invalid mode %q", key)
+	}
+
+	if m.Rows, m.Cols, err = parseSize(parts[0]); err != nil {
+		return
+	}
+	if m.Target, err = strconv.Atoi(parts[2]); err != nil {
+		return
+	}
+	m.Policy = parts[1]
+	m.Undo = parts[3] == "undo"
+
+	return
+}
+
+//NewServer create server which keeps top results of every mode in file
+func NewServer(filename string, top int) (*Server, error) {
+	s := &Server{filename: filename, top: top}
+
+	lb, err := readLeaderBoard(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err == nil {
+		err = lb.keepUnverified(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.LeaderBoard = lb
+	s.Trim(top)
+
+	return s, nil
+}
+
+//Add store verified result in leaderboard, it return rank of result in its mode.
+//Result is kept in memory only after it is written to file.
+func (s *Server) Add(u User) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Contains(u) {
+		return 0, ErrSubmitted
+	}
+
+	lb := LeaderBoard{Users: append(append([]User(nil), s.Users...), u)}
+	lb.Trim(s.top)
+	if err := lb.Write(s.filename); err != nil {
+		return 0, err
+	}
+	s.LeaderBoard = lb
+
+	rank := 0
+	for i, e := range s.Top(u.Mode()) {
+		if bytes.Equal(e.Replay, u.Replay) {
+			rank = i + 1
+		}
+	}
+	return rank, nil
+}
+
+//Handler return HTTP handler of server API:
+//POST /api/submit accepts JSON result with replay, GET /api/modes lists modes,
+//GET /api/leaderboard?mode=KEY return ranked results of mode without replays
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/submit", s.handleSubmit)
+	mux.HandleFunc("/api/modes", s.handleModes)
+	mux.HandleFunc("/api/leaderboard", s.handleLeaderBoard)
+	return mux
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var u User
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmitSize)).Decode(&u); err != nil {
+		writeJSON(w, http.StatusBadRequest, SubmitResponse{Error: err.Error()})
+		return
+	}
+
+	if err := u.Verify(); err != nil {
+		log.Printf("result %d of %s is rejected, %s", u.Score, u.Name, err)
+		writeJSON(w, http.StatusBadRequest, SubmitResponse{Error: err.Error()})
+		return
+	}
+
+	rank, err := s.Add(u)
+	if err == ErrSubmitted {
+		writeJSON(w, http.StatusConflict, SubmitResponse{Error: err.Error(), Duplicate: true})
+		return
+	}
+	if err != nil {
+		log.Printf("failed store result %d of %s, %s", u.Score, u.Name, err)
+		writeJSON(w, http.StatusInternalServerError, SubmitResponse{Error: "failed store result"})
+		return
+	}
+
+	log.Printf("result %d of %s is accepted in mode %s, rank %d", u.Score, u.Name, u.Mode(), rank)
+	writeJSON(w, http.StatusOK, SubmitResponse{Accepted: true, Rank: rank})
+}
+
+func (s *Server) handleModes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	modes := []ModeInfo{}
+	for _, m := range s.Modes() {
+		modes = append(modes, ModeInfo{Key: m.Key(), Name: m.String(), Results: len(s.Top(m))})
+	}
+
+	writeJSON(w, http.StatusOK, modes)
+}
+
+func (s *Server) handleLeaderBoard(w http.ResponseWriter, r *http.Request) {
+	m, err := ParseMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	users := s.Top(m)
+	s.mu.Unlock()
+
+	for i := range users {
+		users[i].Replay = nil
+	}
+	if users == nil {
+		users = []User{}
+	}
+
+	writeJSON(w, http.StatusOK, users)
+}
+
+//writeJSON write value as JSON response with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("failed write response,", err)
+	}
+}
+
+//ServeLeaderBoard run leaderboard server, it is started by command serve-leaderboard, it return exit code
+func ServeLeaderBoard(args []string) int {
+	fs := flag.NewFlagSet("serve-leaderboard", flag.ExitOnError)
+	addr := fs.String("addr", ":8048", "address of HTTP server")
+	file := fs.String("file", filepath.Join(defaultDataDir(), "server-leaderboard"), "file of shared leaderboard")
+	top := fs.Int("top", 100, "count of results kept for every mode")
+	fs.Parse(args)
+
+	if err := os.MkdirAll(filepath.Dir(*file), 0755); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	s, err := NewServer(*file, *top)
+	if err != nil {
+		log.Printf("failed load leaderboard %s, %s", *file, err)
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: serverTimeout,
+		ReadTimeout:       serverTimeout,
+		WriteTimeout:      serverTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+
+	log.Printf("leaderboard server is listening on %s, results are stored in %s", *addr, *file)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
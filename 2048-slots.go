@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sg3des/fizzgui"
+)
+
+//slotsDir is directory with named saves
+var slotsDir = "saves"
+
+//slotExt is extension of named save files, their backups have additional numeric extension
+const slotExt = ".save"
+
+//autosavePrefix starts names of slots where games replaced by loaded ones are kept, players can not use such names
+const autosavePrefix = "autosave"
+
+//autosaveCount is count of kept autosaves, older ones are removed
+const autosaveCount = 5
+
+//slotsPerPage is count of slots shown in slots overlay at once
+const slotsPerPage = 6
+
+//SlotInfo is short description of named save
+type SlotInfo struct {
+	Name    string
+	Score   int
+	MaxTile int
+	Rows    int
+	Cols    int
+	Time    time.Time
+}
+
+//SlotName clean name of slot to be used as file name
+func SlotName(name string) string {
+	name = strings.TrimSpace(name)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == ' ' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+//checkSlotName return error if name of slot chosen by player is empty or reserved for autosaves
+func checkSlotName(name string) error {
+	name = SlotName(name)
+	if name == "" {
+		return fmt.Errorf("name of saved game is empty")
+	}
+	if strings.HasPrefix(strings.ToLower(name), autosavePrefix) {
+		return fmt.Errorf("name %s is reserved for autosaves", name)
+	}
+	return nil
+}
+
+//slotFilename return file name of named save
+func slotFilename(name string) string {
+	return filepath.Join(slotsDir, SlotName(name)+slotExt)
+}
+
+//ListSlots return descriptions of named saves, the newest first
+func ListSlots() (slots []SlotInfo, err error) {
+	files, err := ioutil.ReadDir(slotsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != slotExt {
+			continue
+		}
+		name := strings.TrimSuffix(fi.Name(), slotExt)
+
+		s, err := LoadSlot(name)
+		if err != nil {
+			log.Printf("failed read saved game %s, %s", name, err)
+			continue
+		}
+
+		var max int
+		for _, n := range s.Game.Cells {
+			if n > max {
+				max = n
+			}
+		}
+
+		slots = append(slots, SlotInfo{
+			Name:    name,
+			Score:   s.Game.Score,
+			MaxTile: max,
+			Rows:    s.Rows,
+			Cols:    s.Cols,
+			Time:    s.Time,
+		})
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Time.After(slots[j].Time)
+	})
+
+	return
+}
+
+//SaveSlot write save to named slot, existing slot with the same name is replaced
+func SaveSlot(name string, s *Save) error {
+	if SlotName(name) == "" {
+		return fmt.Errorf("name of saved game is empty")
+	}
+
+	if err := os.MkdirAll(slotsDir, 0755); err != nil {
+		return err
+	}
+
+	data, err := EncodeSave(s)
+	if err != nil {
+		return err
+	}
+
+	return WriteChecked(slotFilename(name), data)
+}
+
+//LoadSlot read save from named slot
+func LoadSlot(name string) (*Save, error) {
+	data, _, err := ReadChecked(slotFilename(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeSave(data)
+}
+
+//RenameSlot rename named save with its backups, autosave may be renamed to keep it
+func RenameSlot(oldname, newname string) error {
+	if err := checkSlotName(newname); err != nil {
+		return err
+	}
+
+	oldfile, newfile := slotFilename(oldname), slotFilename(newname)
+	if _, err := os.Stat(newfile); err == nil {
+		return fmt.Errorf("saved game %s already exists", SlotName(newname))
+	}
+
+	if err := os.Rename(oldfile, newfile); err != nil {
+		return err
+	}
+
+	for n := 1; n <= backupCount; n++ {
+		os.Rename(backupName(oldfile, n), backupName(newfile, n))
+	}
+
+	return nil
+}
+
+//Autosave keep save in new slot named by time, so neither slots of player nor previous autosaves are replaced,
+//only autosaveCount newest autosaves are kept. It return name of slot.
+func Autosave(s *Save, now time.Time) (string, error) {
+	name := autosavePrefix + now.Format("-20060102-150405")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(slotFilename(name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s%s-%d", autosavePrefix, now.Format("-20060102-150405"), n)
+	}
+
+	if err := SaveSlot(name, s); err != nil {
+		return "", err
+	}
+
+	slots, err := ListSlots()
+	if err != nil {
+		return name, err
+	}
+
+	kept := 0
+	for _, slot := range slots {
+		if !strings.HasPrefix(strings.ToLower(slot.Name), autosavePrefix) {
+			continue
+		}
+		if kept++; kept > autosaveCount {
+			if err := DeleteSlot(slot.Name); err != nil {
+				log.Printf("failed remove old autosave %s, %s", slot.Name, err)
+			}
+		}
+	}
+
+	return name, nil
+}
+
+//DeleteSlot remove named save with its backups
+func DeleteSlot(name string) error {
+	filename := slotFilename(name)
+	for n := 1; n <= backupCount; n++ {
+		os.Remove(backupName(filename, n))
+	}
+
+	return os.Remove(filename)
+}
+
+//Slots is overlay with list of named saves
+type Slots struct {
+	Container *fizzgui.Container
+
+	Name  string
+	Infos [slotsPerPage]*fizzgui.Widget
+	Loads [slotsPerPage]*fizzgui.Widget
+	Renms [slotsPerPage]*fizzgui.Widget
+	Dels  [slotsPerPage]*fizzgui.Widget
+
+	status *fizzgui.Widget
+	slots  []SlotInfo
+	page   int
+}
+
+//NewSlots create overlay of named saves, it is opened from menu or by Ctrl+S
+func NewSlots() *Slots {
+	s := new(Slots)
+	s.Container = fizzgui.NewContainer("slots", "5%", "10%", "90%", "85%")
+	s.Container.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
+	s.Container.Zorder = 3
+	s.Container.Hidden = true
+
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	title := s.Container.NewText("Saved games")
+	title.Layout.SetWidth("100%")
+	title.TextAlign = fizzgui.TALIGN_CENTER
+	title.Style.TextColor = white
+
+	name := s.Container.NewText("Name:")
+	name.Layout.SetWidth("20%")
+	name.Style.TextColor = white
+	name.Font = TextFontSmall
+
+	input := s.Container.NewInput("slotname", &s.Name, nil)
+	input.Layout.SetWidth("55%")
+	input.Style.TextColor = white
+	input.Font = TextFontSmall
+
+	save := s.Container.NewButton("SAVE", s.Save)
+	save.Layout.SetWidth("25%")
+	save.Style.TextColor = white
+	save.Font = TextFontSmall
+
+	for i := 0; i < slotsPerPage; i++ {
+		i := i
+
+		s.Infos[i] = s.Container.NewText("")
+		s.Infos[i].Layout.SetWidth("55%")
+		s.Infos[i].Style.TextColor = white
+		s.Infos[i].Font = TextFontSmall
+
+		s.Loads[i] = s.Container.NewButton("LOAD", func(_ *fizzgui.Widget) { s.Load(i) })
+		s.Loads[i].Layout.SetWidth("15%")
+		s.Loads[i].Font = TextFontSmall
+
+		s.Renms[i] = s.Container.NewButton("NAME", func(_ *fizzgui.Widget) { s.Rename(i) })
+		s.Renms[i].Layout.SetWidth("15%")
+		s.Renms[i].Font = TextFontSmall
+
+		s.Dels[i] = s.Container.NewButton("DEL", func(_ *fizzgui.Widget) { s.Delete(i) })
+		s.Dels[i].Layout.SetWidth("15%")
+		s.Dels[i].Font = TextFontSmall
+	}
+
+	s.status = s.Container.NewText("")
+	s.status.Layout.SetWidth("100%")
+	s.status.Style.TextColor = white
+	s.status.Font = TextFontSmall
+
+	prev := s.Container.NewButton("<", func(_ *fizzgui.Widget) { s.Page(-1) })
+	prev.Layout.SetWidth("20%")
+	prev.Layout.PositionFixed = true
+	prev.Layout.VAlign = fizzgui.VAlignBottom
+
+	closeBtn := s.Container.NewButton("Close", s.Hide)
+	closeBtn.Layout.SetX("25%")
+	closeBtn.Layout.SetWidth("50%")
+	closeBtn.Layout.PositionFixed = true
+	closeBtn.Layout.VAlign = fizzgui.VAlignBottom
+	closeBtn.Font = TextFontSmall
+
+	next := s.Container.NewButton(">", func(_ *fizzgui.Widget) { s.Page(1) })
+	next.Layout.SetX("80%")
+	next.Layout.SetWidth("20%")
+	next.Layout.PositionFixed = true
+	next.Layout.VAlign = fizzgui.VAlignBottom
+
+	return s
+}
+
+//Toggle show or hide overlay
+func (s *Slots) Toggle(_ *fizzgui.Widget) {
+	if !s.Container.Hidden {
+		s.Hide(nil)
+		return
+	}
+
+	menu.Hide()
+	s.Container.Hidden = false
+	s.status.Text = ""
+	s.Update()
+}
+
+func (s *Slots) Hide(_ *fizzgui.Widget) {
+	s.Container.Hidden = true
+}
+
+//Update reload list of named saves and refresh current page
+func (s *Slots) Update() {
+	var err error
+	if s.slots, err = ListSlots(); err != nil {
+		s.status.Text = err.Error()
+	}
+
+	if s.page*slotsPerPage >= len(s.slots) {
+		s.page = 0
+	}
+
+	for i := 0; i < slotsPerPage; i++ {
+		slot, ok := s.slot(i)
+
+		s.Infos[i].Hidden = !ok
+		s.Loads[i].Hidden = !ok
+		s.Renms[i].Hidden = !ok
+		s.Dels[i].Hidden = !ok
+
+		if ok {
+			s.Infos[i].Text = fmt.Sprintf("%s  %d  %d  %s  %s", slot.Name, slot.Score, slot.MaxTile,
+				sizeName(slot.Rows, slot.Cols), slot.Time.Format("2006-01-02 15:04"))
+		}
+	}
+}
+
+//slot return slot shown in i-th line of current page
+func (s *Slots) slot(i int) (SlotInfo, bool) {
+	i += s.page * slotsPerPage
+	if i >= len(s.slots) {
+		return SlotInfo{}, false
+	}
+	return s.slots[i], true
+}
+
+//Page switch to previous or next page of list
+func (s *Slots) Page(delta int) {
+	if p := s.page + delta; p >= 0 && p*slotsPerPage < len(s.slots) {
+		s.page = p
+	}
+	s.Update()
+}
+
+//Save current game to slot named by input
+func (s *Slots) Save(_ *fizzgui.Widget) {
+	if table == nil {
+		return
+	}
+
+	if err := checkSlotName(s.Name); err != nil {
+		s.status.Text = err.Error()
+		return
+	}
+
+	if err := SaveSlot(s.Name, NewSave(table)); err != nil {
+		s.status.Text = err.Error()
+		return
+	}
+
+	s.status.Text = fmt.Sprintf("Game is saved as %s", SlotName(s.Name))
+	s.Update()
+}
+
+//Load game from slot shown in i-th line, current game is replaced
+func (s *Slots) Load(i int) {
+	slot, ok := s.slot(i)
+	if !ok {
+		return
+	}
+
+	save, err := LoadSlot(slot.Name)
+	if err != nil {
+		s.status.Text = err.Error()
+		return
+	}
+
+	ReplaceGame(save)
+	s.Hide(nil)
+}
+
+//Rename slot shown in i-th line to name from input
+func (s *Slots) Rename(i int) {
+	slot, ok := s.slot(i)
+	if !ok {
+		return
+	}
+
+	if err := RenameSlot(slot.Name, s.Name); err != nil {
+		s.status.Text = err.Error()
+		return
+	}
+
+	s.status.Text = fmt.Sprintf("%s is renamed to %s", slot.Name, SlotName(s.Name))
+	s.Update()
+}
+
+//Delete slot shown in i-th line
+func (s *Slots) Delete(i int) {
+	slot, ok := s.slot(i)
+	if !ok {
+		return
+	}
+
+	if err := DeleteSlot(slot.Name); err != nil {
+		s.status.Text = err.Error()
+		return
+	}
+
+	s.status.Text = fmt.Sprintf("%s is deleted", slot.Name)
+	s.Update()
+}
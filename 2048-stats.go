@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sg3des/2048/engine"
+	"github.com/sg3des/fizzgui"
+)
+
+var statsFilename = "statistics"
+
+//idleTimeout limits time between moves counted as played, longer pauses are counted as idleTimeout
+const idleTimeout = 30 * time.Second
+
+//Stats is lifetime statistics of player
+type Stats struct {
+	GamesPlayed int
+	GamesWon    int
+
+	//HighestTile is the biggest tile ever reached, Tiles is count of games by their biggest tile
+	HighestTile int
+	Tiles       map[int]int
+
+	//Scores is final scores of all played games
+	Scores []int
+
+	Moves int
+
+	//Merges is count of merges by value of resulting tile
+	Merges map[int]int
+
+	//Undos is count of undone moves, GamesUndone is count of games where undo was used
+	Undos       int
+	GamesUndone int
+
+	TimePlayed time.Duration
+
+	//Best is personal best score of every mode by key of mode, only verified results are counted
+	Best map[string]int
+}
+
+//LoadStats read statistics from file, missing or damaged file gives empty statistics
+func LoadStats() *Stats {
+	s := new(Stats)
+
+	data, _, err := ReadChecked(statsFilename, nil)
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(s)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("failed read statistics,", err)
+	}
+
+	if s.Tiles == nil {
+		s.Tiles = make(map[int]int)
+	}
+	if s.Merges == nil {
+		s.Merges = make(map[int]int)
+	}
+	if s.Best == nil {
+		s.Best = make(map[string]int)
+	}
+
+	return s
+}
+
+//Save write statistics to file
+func (s *Stats) Save() error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return err
+	}
+
+	return WriteChecked(statsFilename, buf.Bytes())
+}
+
+//Move count move, its merges and time d spent on it
+func (s *Stats) Move(r *engine.MoveResult, d time.Duration) {
+	if !r.Moved() {
+		return
+	}
+
+	s.Moves++
+	for _, m := range r.Merges {
+		s.Merges[m.N]++
+	}
+	s.TimePlayed += d
+}
+
+//Undo count undone move
+func (s *Stats) Undo() {
+	s.Undos++
+}
+
+//FinishGame count result of game on table, games without moves are not counted
+func (s *Stats) FinishGame(t *Table) {
+	if t.Replay == nil || len(t.Replay.Steps) == 0 {
+		return
+	}
+
+	s.GamesPlayed++
+	if t.Won() || t.keepPlaying {
+		s.GamesWon++
+	}
+	if t.Undone {
+		s.GamesUndone++
+	}
+
+	max := t.MaxTile()
+	s.Tiles[max]++
+	if max > s.HighestTile {
+		s.HighestTile = max
+	}
+
+	s.Scores = append(s.Scores, t.Score)
+
+	if err := s.Save(); err != nil {
+		log.Println("failed save statistics,", err)
+	}
+}
+
+//RecordBest keep verified result as personal best of its mode if it is better than previous one
+func (s *Stats) RecordBest(u User) {
+	key := u.Mode().Key()
+	if u.Score <= s.Best[key] {
+		return
+	}
+
+	s.Best[key] = u.Score
+	if err := s.Save(); err != nil {
+		log.Println("failed save statistics,", err)
+	}
+}
+
+//AverageScore return average of final scores
+func (s *Stats) AverageScore() int {
+	if len(s.Scores) == 0 {
+		return 0
+	}
+
+	var sum int
+	for _, score := range s.Scores {
+		sum += score
+	}
+	return sum / len(s.Scores)
+}
+
+//MedianScore return median of final scores
+func (s *Stats) MedianScore() int {
+	if len(s.Scores) == 0 {
+		return 0
+	}
+
+	scores := append([]int(nil), s.Scores...)
+	sort.Ints(scores)
+
+	n := len(scores)
+	if n%2 == 0 {
+		return (scores[n/2-1] + scores[n/2]) / 2
+	}
+	return scores[n/2]
+}
+
+//countsList format counts by tile value from the biggest value, at most limit values are listed
+func countsList(counts map[int]int, limit int) string {
+	var values []int
+	for n := range counts {
+		values = append(values, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(values)))
+
+	var list []string
+	for i, n := range values {
+		if i == limit {
+			break
+		}
+		list = append(list, fmt.Sprintf("%d:%d", n, counts[n]))
+	}
+
+	if len(list) == 0 {
+		return "-"
+	}
+	return strings.Join(list, "  ")
+}
+
+//Lines return statistics as lines of text
+func (s *Stats) Lines() []string {
+	var wonPercent int
+	if s.GamesPlayed > 0 {
+		wonPercent = s.GamesWon * 100 / s.GamesPlayed
+	}
+
+	return []string{
+		fmt.Sprintf("Games played: %d", s.GamesPlayed),
+		fmt.Sprintf("Games won: %d (%d%%)", s.GamesWon, wonPercent),
+		fmt.Sprintf("Highest tile: %d", s.HighestTile),
+		fmt.Sprintf("Best tiles: %s", countsList(s.Tiles, 5)),
+		fmt.Sprintf("Average score: %d", s.AverageScore()),
+		fmt.Sprintf("Median score: %d", s.MedianScore()),
+		fmt.Sprintf("Moves: %d", s.Moves),
+		fmt.Sprintf("Merges: %s", countsList(s.Merges, 5)),
+		fmt.Sprintf("Undo: %d moves in %d games", s.Undos, s.GamesUndone),
+		fmt.Sprintf("Time played: %s", s.TimePlayed.Truncate(time.Second)),
+	}
+}
+
+//StatsScreen is overlay with lifetime statistics
+type StatsScreen struct {
+	Container *fizzgui.Container
+	Lines     []*fizzgui.Widget
+}
+
+//NewStatsScreen create statistics overlay, it is opened by button STATS in header
+func NewStatsScreen() *StatsScreen {
+	s := new(StatsScreen)
+	s.Container = fizzgui.NewContainer("statistics", "10%", "10%", "80%", "80%")
+	s.Container.Zorder = 3
+	s.Container.Hidden = true
+
+	title := s.Container.NewText("Statistics")
+	title.TextAlign = fizzgui.TALIGN_CENTER
+	title.Layout.SetWidth("100%")
+
+	for range stats.Lines() {
+		line := s.Container.NewText("")
+		line.Font = TextFontSmall
+		line.Layout.SetWidth("100%")
+		s.Lines = append(s.Lines, line)
+	}
+
+	closeBtn := s.Container.NewButton("Close", s.Toggle)
+	closeBtn.Layout.SetWidth("50%")
+	closeBtn.Layout.PositionFixed = true
+	closeBtn.Layout.HAlign = fizzgui.HAlignCenter
+	closeBtn.Layout.VAlign = fizzgui.VAlignBottom
+	closeBtn.Font = TextFontSmall
+
+	return s
+}
+
+//Toggle show or hide statistics
+func (s *StatsScreen) Toggle(_ *fizzgui.Widget) {
+	if !s.Container.Hidden {
+		s.Container.Hidden = true
+		return
+	}
+	s.Container.Hidden = false
+
+	for i, line := range stats.Lines() {
+		s.Lines[i].Text = line
+	}
+}
@@ -20,7 +20,9 @@ var (
 
 	TextFont      *fizzgui.Font
 	TextFontSmall *fizzgui.Font
+	TextFontTiny  *fizzgui.Font
 	NumsFont      *fizzgui.Font
+	NumsFontSmall *fizzgui.Font
 )
 
 func NewWindow(title string, w, h int) error {
@@ -43,15 +45,42 @@ func NewWindow(title string, w, h int) error {
 		return fmt.Errorf("Failed to load the Text font, reason: %s", err)
 	}
 
+	TextFontTiny, err = fizzgui.NewFont("Tiny", fontfilename, 14, fizzgui.FontGlyphs)
+	if err != nil {
+		return fmt.Errorf("Failed to load the Text font, reason: %s", err)
+	}
+
 	//load a default font
 	NumsFont, err = fizzgui.NewFont("Nums", fontfilename, 41, "012345689")
 	if err != nil {
 		return fmt.Errorf("Failed to load the Default font, reason: %s", err)
 	}
 
+	//font for numbers on big boards
+	NumsFontSmall, err = fizzgui.NewFont("NumsSmall", fontfilename, 24, "0123456789")
+	if err != nil {
+		return fmt.Errorf("Failed to load the Small font, reason: %s", err)
+	}
+
 	return nil
 }
 
+//windowSize calculate size of window for board rows x cols,
+//cells are squares and window is not narrower than 300px
+func windowSize(rows, cols int) (w, h int) {
+	max := rows
+	if cols > max {
+		max = cols
+	}
+
+	cell := 500 / max
+	if cell*cols < 300 {
+		cell = 300 / cols
+	}
+
+	return cell * cols, cell*rows + 100
+}
+
 func RenderLoop() {
 	for {
 		t := time.Now()
@@ -69,7 +98,10 @@ func RenderLoop() {
 		glfw.PollEvents()
 
 		dt := float32(time.Now().Sub(t).Seconds())
-		Transitions(dt)
+		playback.Update(dt)
+		Transitions(dt * playback.TimeScale())
+		header.UpdateSubmissions()
+		header.CheckLeaderBoard()
 
 		if window.ShouldClose() {
 			Close()
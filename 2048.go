@@ -2,16 +2,16 @@ package main
 
 import (
 	"encoding/gob"
-	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/sg3des/2048/engine"
 	"github.com/sg3des/fizzgui"
 )
 
@@ -19,143 +19,323 @@ var (
 	header  *Header
 	table   *Table
 	endgame *EndGame
+	victory *Victory
+	notice  *Notice
+	menu    *Menu
+	slots   *Slots
 
-	prevMove *TableState
+	profiles *Profiles
+	playback *Playback
 
-	saveFile     *os.File
-	saveFilename = "2048.save"
+	stats       *Stats
+	statsScreen *StatsScreen
+
+	settings    Settings
+	submissions *Queue
+
+	//boardRows and boardCols is size of board for next new game
+	boardRows = engine.DefaultSize
+	boardCols = engine.DefaultSize
+
+	//gameSeed is seed of next new game, 0 means random seed
+	gameSeed int64
+
+	//spawnPolicy is name of spawn policy for next new game
+	spawnPolicy = engine.Classic
+
+	//target is value of tile which wins next new game
+	target = engine.DefaultTarget
+
+	//undoDepth is count of moves which can be undone in next new game
+	undoDepth = engine.DefaultUndoDepth
 )
 
-type TableState struct {
-	Items [16]int
-	Score int
+//commands are run without window when name of command is the first argument
+var commands = map[string]func(args []string) int{
+	"serve-leaderboard":  ServeLeaderBoard,
+	"export-leaderboard": ExportLeaderBoard,
+	"import-leaderboard": ImportLeaderBoard,
+	"merge-leaderboard":  MergeLeaderBoard,
 }
 
 func init() {
 	log.SetFlags(log.Lshortfile)
-	os.Chdir(filepath.Dir(os.Args[0]))
 }
 
 func main() {
-	err := NewWindow("2048", 500, 600)
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	size := flag.String("size", sizeName(boardRows, boardCols), fmt.Sprintf("size of board for new game, N or ROWSxCOLS, from %d to %d", engine.MinSize, engine.MaxSize))
+	flag.Int64Var(&gameSeed, "seed", 0, "seed of new game, the same seed and moves reproduce the same game, 0 is random seed")
+	flag.StringVar(&spawnPolicy, "spawn", spawnPolicy, fmt.Sprintf("spawn policy of new game, one of %s", strings.Join(engine.PolicyNames(), ", ")))
+	flag.IntVar(&target, "target", target, fmt.Sprintf("value of tile which wins new game, power of two from %d to %d", engine.MinTarget, engine.MaxTarget))
+	flag.IntVar(&undoDepth, "undo", undoDepth, "count of moves which can be undone in new game, 0 disables undo")
+	data := flag.String("data", defaultDataDir(), "directory of saves and leaderboard, also may be set by $"+envDataDir)
+	verify := flag.String("verify", "", "verify replay file without window: re-play it by rules of game and print final score")
+	replay := flag.String("replay", "", "replay file to show, replay of current game is written next to save")
+	position := flag.String("position", "", "JSON file with position which starts new game, see README")
+	leaderboardURL := flag.String("leaderboard-url", "", "address of shared leaderboard server, overrides leaderboard_url of settings")
+	config := flag.String("config", defaultConfigDir(), "directory of settings, also may be set by $"+envConfigDir)
+	flag.Parse()
+
+	if *verify != "" {
+		os.Exit(VerifyFile(*verify))
+	}
+
+	if err := SetupDirs(*data, *config); err != nil {
+		log.Fatalln("failed create directories of game,", err)
+	}
+	settings = LoadSettings()
+	if *leaderboardURL != "" {
+		settings.LeaderboardURL = *leaderboardURL
+	}
+
+	if _, err := SetupProfiles(settings.Profile); err != nil {
+		log.Fatalln("failed setup profiles,", err)
+	}
+	stats = LoadStats()
+
+	submissions = LoadQueue(queueFilename)
+	go submissions.Run()
+
+	if !engine.ValidTarget(target) {
+		log.Fatalf("invalid target %d, it should be power of two from %d to %d", target, engine.MinTarget, engine.MaxTarget)
+	}
+
+	if _, ok := engine.Policy(spawnPolicy); !ok {
+		log.Fatalf("unknown spawn policy %q, it should be one of %s", spawnPolicy, strings.Join(engine.PolicyNames(), ", "))
+	}
+
+	var err error
+	boardRows, boardCols, err = parseSize(*size)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	gob.Register(LeaderBoard{})
-	gob.Register(TableState{})
-
-	saveFile, err = os.OpenFile(saveFilename, os.O_CREATE|os.O_RDWR, 0644)
+	w, h := windowSize(boardRows, boardCols)
+	err = NewWindow("2048", w, h)
 	if err != nil {
-		log.Println("failed open file with saved game state, ", err)
+		log.Fatalln(err)
 	}
 
+	gob.Register(LeaderBoard{})
+	gob.Register(TableState{})
+
+	menu = NewMenu()
+	playback = NewPlayback()
+	statsScreen = NewStatsScreen()
 	header = NewHeader()
 	endgame = NewEndGame()
+	victory = NewVictory()
+	notice = NewNotice()
+	slots = NewSlots()
+	profiles = NewProfiles()
 	LoadGame()
 
+	//player is asked on start, unless game or replay is specified by flags
+	if *position == "" && gameSeed == 0 && *replay == "" {
+		profiles.Show()
+	}
+
+	//specified position or seed always starts new game
+	switch {
+	case *position != "":
+		s, err := ReadPosition(*position)
+		if err != nil {
+			log.Fatalf("failed load position %s, %s", *position, err)
+		}
+		ReplaceGame(s)
+
+	case gameSeed != 0:
+		NewGame(nil)
+	}
+
+	if *replay != "" {
+		if err := playback.PlayFile(*replay); err != nil {
+			log.Fatalf("failed load replay %s, %s", *replay, err)
+		}
+	}
+
 	RenderLoop()
 }
 
+//FinishGame store result of current game in leaderboard and statistics, it is done once per game:
+//when game is lost or when new game replaces it. Game continued by undo after game over is not stored again.
+func FinishGame() {
+	if table == nil || playback.Active() {
+		return
+	}
+	if table.finished {
+		return
+	}
+	table.finished = true
+
+	if header.curr.Score > 0 {
+		header.writeLeaderBoard()
+	}
+	stats.FinishGame(table)
+}
+
+//GameOver finish lost game and show overlay, ended game is not restored on next start, but its replay is kept for review
+func GameOver() {
+	FinishGame()
+	endgame.Show()
+
+	if err := RemoveChecked(saveFilename); err != nil {
+		log.Println("failed clear saved game,", err)
+	}
+	if err := SaveReplay(); err != nil {
+		log.Println("failed save replay,", err)
+	}
+}
+
 //NewGame - create new table and fill it with 2 items
 func NewGame(_ *fizzgui.Widget) {
 	if table != nil {
+		FinishGame()
 		table.Container.Close()
 	}
 
-	header.NewGame()
+	header.NewGame(NewMode(boardRows, boardCols, Rules{Policy: spawnPolicy, Target: target, Undo: undoDepth}))
 	endgame.Hide()
+	victory.Hide()
+	menu.Hide()
 
-	table = NewTable()
-	table.FillRandomItem()
-	table.FillRandomItem()
+	seed := gameSeed
+	if seed == 0 {
+		seed = engine.NewSeed()
+	}
+	gameSeed = 0
+
+	table = NewTable(boardRows, boardCols, seed)
+	table.Policy, _ = engine.Policy(spawnPolicy)
+	table.Target = target
+	table.History = engine.NewHistory(undoDepth)
+	for i := 0; i < engine.StartTiles; i++ {
+		table.FillRandomItem()
+	}
+	table.Replay = engine.NewReplay(table.Board)
 	table.Redraw()
 }
 
-//LoadGame restore save state
-func LoadGame() {
-	var state = TableState{}
+//parseSize parse size of board from string N or ROWSxCOLS
+func parseSize(s string) (rows, cols int, err error) {
+	rs, cs := s, s
+	if i := strings.IndexAny(s, "xX"); i >= 0 {
+		rs, cs = s[:i], s[i+1:]
+	}
 
-	err := gob.NewDecoder(saveFile).Decode(&state)
+	rows, err = strconv.Atoi(rs)
+	if err == nil {
+		cols, err = strconv.Atoi(cs)
+	}
 	if err != nil {
-		NewGame(nil)
-		return
+		return 0, 0, fmt.Errorf("invalid board size %q, it should be N or ROWSxCOLS", s)
 	}
 
-	table = NewTable()
-	for i, n := range state.Items {
-		table.Items[i].N = n
+	if !engine.ValidSize(rows, cols) {
+		return 0, 0, fmt.Errorf("invalid board size %s, rows and columns should be from %d to %d", s, engine.MinSize, engine.MaxSize)
 	}
-	table.Redraw()
-
-	header.curr.Score = state.Score
-	header.UpdateCurr()
 
+	return
 }
 
-//SaveGame write state to file
-func SaveGame() error {
-	if table == nil {
-		return errors.New("table is nil")
-	}
-
-	saveFile.Truncate(0)
-	saveFile.Seek(0, 0)
-
-	return gob.NewEncoder(saveFile).Encode(table.TableState())
+//sizeName return size of board as string ROWSxCOLS
+func sizeName(rows, cols int) string {
+	return fmt.Sprintf("%dx%d", rows, cols)
 }
 
-//Table is main struct contains matrix 4x4
+//Table is main struct, it renders engine board
 type Table struct {
+	*engine.Board
+	History *engine.History
+
+	//Replay is record of game which leads to current board
+	Replay *engine.Replay
+
 	Container *fizzgui.Container
-	Items     [16]*Item
+	Items     []*Item
+
+	//cellH and cellW is height and width of one cell in percents of table
+	cellH float32
+	cellW float32
+
+	lost        bool
+	keepPlaying bool
 
-	rand *rand.Rand
-	lost bool
+	//finished is true when result of game is stored
+	finished bool
+
+	//Duration is time spent on game, Undone is true if undo was used in game
+	Duration time.Duration
+	Undone   bool
+
+	lastMove time.Time
 }
 
-//NewTable initialize table
-func NewTable() *Table {
+//NewTable initialize table rows x cols with seed of random numbers, and resize window to its shape
+func NewTable(rows, cols int, seed int64) *Table {
+	w, h := windowSize(rows, cols)
+	window.SetSize(w, h)
+	window.SetTitle(fmt.Sprintf("2048 - seed %d", seed))
+
 	t := &Table{
-		Container: fizzgui.NewContainer("table", "1", "100", "100%", "500px"),
-		rand:      rand.New(rand.NewSource(time.Now().Unix())),
+		Board:     engine.NewBoard(rows, cols, seed),
+		Container: fizzgui.NewContainer("table", "1", "100", "100%", fmt.Sprintf("%dpx", h-100)),
+		Items:     make([]*Item, rows*cols),
+		cellH:     100 / float32(rows),
+		cellW:     100 / float32(cols),
 		lost:      false,
 	}
 	t.Container.Style.BackgroundColor = fizzgui.Color(187, 173, 160, 255)
 
-	for row := 0; row < 4; row++ {
-		for col := 0; col < 4; col++ {
-			item := t.NewItem()
-			item.transSrc = TransSrc{X: float32(col * 25), Y: float32(row * 25)}
+	for i := range t.Items {
+		item := t.NewItem()
+		item.transSrc.Y, item.transSrc.X = t.cellPos(i)
 
-			t.Items[4*row+col] = item
-		}
+		t.Items[i] = item
 	}
 
 	return t
 }
 
-//Item is square with number on table
+//cellPos return position of cell in percents of table
+func (t *Table) cellPos(i int) (row, col float32) {
+	return float32(i/t.Cols) * t.cellH, float32(i%t.Cols) * t.cellW
+}
+
+//percent format value for layout
+func percent(v float32) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+//Item is square with number on table, it draw board tile placed in the same cell
 type Item struct {
-	N          int
 	btn        *fizzgui.Widget
 	transition bool
 	transSrc   TransSrc
 }
 
-//TransSrc contains X,Y and Size values for transitions
+//TransSrc contains X,Y position in percents and S scale from 0 to 1 for transitions
 type TransSrc struct {
 	Y, X, S float32
 }
 
-//NewItem created new number square button contains 2 or 4 in any random empty position
+//NewItem created new number square button
 func (t *Table) NewItem() *Item {
 	item := &Item{}
 
-	item.btn = t.Container.NewButton(strconv.Itoa(item.N), nil)
+	item.btn = t.Container.NewButton("", nil)
 	item.btn.Hidden = true
 	item.btn.Layout.PositionFixed = true
 	item.btn.Font = NumsFont
+	if t.Rows > 5 || t.Cols > 5 {
+		item.btn.Font = NumsFontSmall
+	}
 
 	item.btn.StyleHover = fizzgui.Style{}
 	item.btn.Style.BorderWidth = 0
@@ -167,53 +347,132 @@ func (t *Table) NewItem() *Item {
 }
 
 func (t *Table) TableState() *TableState {
-	ts := new(TableState)
-	for i, item := range t.Items {
-		ts.Items[i] = item.N
+	ts := &TableState{
+		Cells: t.Cells(),
+		Score: t.Score,
+		Seed:  t.Seed,
+		Rand:  t.RandState(),
+
+		KeepPlaying: t.keepPlaying,
+
+		History: t.History,
+
+		Duration: t.Duration,
+		Undone:   t.Undone,
+		Finished: t.finished,
 	}
-	ts.Score = header.curr.Score
+
+	if t.Replay != nil {
+		var err error
+		if ts.Replay, err = t.Replay.MarshalBinary(); err != nil {
+			log.Println("failed encode replay,", err)
+		}
+	}
+
 	return ts
 }
 
 func (t *Table) RestoreState(ts *TableState) {
-	for i, n := range ts.Items {
-		t.Items[i].N = n
-	}
+	t.keepPlaying = ts.KeepPlaying
+	t.Duration, t.Undone = ts.Duration, ts.Undone
+	t.finished = ts.Finished
+	t.Restore(engine.State{Cells: ts.Cells, Score: ts.Score, Rand: ts.Rand})
+}
+
+//Restore board from snapshot and update header
+func (t *Table) Restore(s engine.State) {
+	t.Board.Restore(s)
 	t.Redraw()
 
-	header.curr.Score = ts.Score
+	header.curr.Score = t.Score
+	header.curr.Rows, header.curr.Cols = t.Rows, t.Cols
+	header.curr.Won = t.keepPlaying || t.Won()
 	header.UpdateCurr()
 }
 
+//played return time since previous move, pauses longer than idleTimeout are counted as idleTimeout
+func (t *Table) played() time.Duration {
+	now := time.Now()
+	defer func() { t.lastMove = now }()
+
+	if t.lastMove.IsZero() {
+		return 0
+	}
+
+	if d := now.Sub(t.lastMove); d < idleTimeout {
+		return d
+	}
+	return idleTimeout
+}
+
+//Undo restore previous state of board, it is possible after game over too
+func (t *Table) Undo() {
+	s, ok := t.History.Undo(t.State())
+	if !ok {
+		return
+	}
+	t.Replay.Undo()
+	t.Undone = true
+	stats.Undo()
+	t.afterHistory(s)
+}
+
+//Redo restore undone state of board
+func (t *Table) Redo() {
+	s, ok := t.History.Redo(t.State())
+	if !ok {
+		return
+	}
+	t.Replay.Redo()
+	t.afterHistory(s)
+}
+
+//afterHistory restore state from history, hide overlays and save game
+func (t *Table) afterHistory(s engine.State) {
+	t.Restore(s)
+	t.lost = !t.CanMove()
+
+	endgame.Hide()
+	victory.Hide()
+	if t.lost {
+		GameOver()
+		return
+	}
+
+	if err := SaveGame(); err != nil {
+		log.Println("failed save game")
+	}
+}
+
 //Redraw func update values, positions and styles of items
 func (t *Table) Redraw() {
 	for i, item := range t.Items {
+		n := t.Tiles[i].N
 
-		if item.N == 0 {
+		if n == 0 {
 			item.btn.Hidden = true
 			continue
 		}
 
 		item.btn.Hidden = false
-		item.btn.Text = strconv.Itoa(item.N)
+		item.btn.Text = strconv.Itoa(n)
 
 		if !item.transition {
-			row := fmt.Sprintf("%d%%", i/4*25)
-			col := fmt.Sprintf("%d%%", i%4*25)
+			row, col := t.cellPos(i)
 
-			item.btn.Layout.SetX(col)
-			item.btn.Layout.SetY(row)
-			item.btn.Layout.SetWidth("25%")
-			item.btn.Layout.SetHeight("25%")
+			item.btn.Layout.SetX(percent(col))
+			item.btn.Layout.SetY(percent(row))
+			item.btn.Layout.SetWidth(percent(t.cellW))
+			item.btn.Layout.SetHeight(percent(t.cellH))
 		}
 
-		if item.N < 8 {
+		if n < 8 {
 			item.btn.Style.TextColor = fizzgui.Color(80, 80, 80, 255)
 		} else {
 			item.btn.Style.TextColor = fizzgui.Color(249, 246, 241, 255)
 		}
 
-		switch item.N {
+		switch n {
 		case 2:
 			item.btn.Style.BackgroundColor = fizzgui.Color(238, 228, 218, 255)
 		case 4:
@@ -240,25 +499,6 @@ func (t *Table) Redraw() {
 	}
 }
 
-//Dump is print value of items 4x4 to stdout
-func TableDump(items [16]*Item) {
-	for i, item := range items {
-		for _i, _item := range items {
-			if item == _item && i != _i {
-				log.Fatalln("item equal %d == %d", i, _i)
-			}
-		}
-	}
-
-	for r := 0; r < 4; r++ {
-		for c := 0; c < 4; c++ {
-			i := r*4 + c
-			fmt.Printf("%2d ", items[i].N)
-		}
-		fmt.Println()
-	}
-}
-
 //Transitions is handle animations
 func Transitions(dt float32) {
 	if table == nil {
@@ -272,8 +512,7 @@ func Transitions(dt float32) {
 			continue
 		}
 
-		row := float32(i / 4 * 25)
-		col := float32(i % 4 * 25)
+		row, col := table.cellPos(i)
 
 		var rowEqual bool
 		if row > item.transSrc.Y+dt {
@@ -298,10 +537,10 @@ func Transitions(dt float32) {
 		}
 
 		var widthEqual bool
-		if item.transSrc.S < 25-dt {
-			item.transSrc.S += dt / 4
+		if item.transSrc.S < 1-dt/100 {
+			item.transSrc.S += dt / 100
 		} else {
-			item.transSrc.S = 25
+			item.transSrc.S = 1
 			widthEqual = true
 		}
 
@@ -309,284 +548,171 @@ func Transitions(dt float32) {
 			item.transition = false
 		}
 
+		w := table.cellW * item.transSrc.S
+		h := table.cellH * item.transSrc.S
 		if !widthEqual {
-			col += 12.5 - item.transSrc.S/2
-			row += 12.5 - item.transSrc.S/2
+			col += table.cellW/2 - w/2
+			row += table.cellH/2 - h/2
 		}
 
-		item.btn.Layout.SetX(fmt.Sprintf("%.0f%%", col))
-		item.btn.Layout.SetY(fmt.Sprintf("%.0f%%", row))
-		item.btn.Layout.SetWidth(fmt.Sprintf("%0.0f%%", item.transSrc.S))
-		item.btn.Layout.SetHeight(fmt.Sprintf("%0.0f%%", item.transSrc.S))
+		item.btn.Layout.SetX(percent(col))
+		item.btn.Layout.SetY(percent(row))
+		item.btn.Layout.SetWidth(percent(w))
+		item.btn.Layout.SetHeight(percent(h))
 	}
 }
 
-//FillRandomItem - fill random empty position on table with number 2 or 4
+//FillRandomItem - fill one empty position on table chosen by spawn policy
 func (t *Table) FillRandomItem() {
-	var empty []int
-	for i, item := range t.Items {
-		if item.N == 0 {
-			empty = append(empty, i)
-		}
-	}
-
-	if len(empty) == 0 {
-		return
+	if i := t.SpawnTile(); i >= 0 {
+		t.appear(i)
 	}
-
-	i := empty[t.rand.Intn(len(empty))]
-	table.FillItem(i, t.newNum())
 }
 
 //FillItem set value to item on table
 func (t *Table) FillItem(i, num int) {
-	item := t.Items[i]
+	t.Set(i, num)
+	t.appear(i)
+}
 
-	item.N = num
+//appear prepare transition of new item grown from the center of cell
+func (t *Table) appear(i int) {
+	item := t.Items[i]
 	item.transition = true
 	item.transSrc.S = 0
-	item.transSrc.Y = float32(i / 4 * 25)
-	item.transSrc.X = float32(i % 4 * 25)
-}
-
-// //newNum return new number 2 or 4
-func (t *Table) newNum() int {
-	if t.rand.Uint32()%2 == 0 {
-		return 2
-	}
-	return 4
-}
-
-func (t *Table) MoveLeft() (moves, score int) {
-	for r := 0; r < 4; r++ {
-		l := t.GetRow(r).Calculate()
-		moves += t.PutRow(r, l)
-		score += l.Score
-	}
-	return
+	item.transSrc.Y, item.transSrc.X = t.cellPos(i)
 }
 
-func (t *Table) MoveRight() (moves, score int) {
-	for r := 0; r < 4; r++ {
-		l := t.GetRow(r).Reverse().Calculate().Reverse()
-		moves += t.PutRow(r, l)
-		score += l.Score
-	}
-	return
+//Play move tiles on board, spawn new ones and prepare transitions described by result of move
+func (t *Table) Play(d engine.Direction) *engine.MoveResult {
+	r := t.Board.Play(d)
+	t.Animate(r)
+	return r
 }
 
-func (t *Table) MoveUp() (moves, score int) {
-	for c := 0; c < 4; c++ {
-		l := t.GetCol(c).Calculate()
-		moves += t.PutCol(c, l)
-		score += l.Score
+//Animate prepare transitions of slides and spawns described by result of move
+func (t *Table) Animate(r *engine.MoveResult) {
+	for _, slide := range r.Slides {
+		item := t.Items[slide.To]
+		item.transition = true
+		item.transSrc.Y, item.transSrc.X = t.cellPos(slide.From)
+		item.transSrc.S = 1
 	}
-	return
-}
 
-func (t *Table) MoveDown() (moves, score int) {
-	for c := 0; c < 4; c++ {
-		l := t.GetCol(c).Reverse().Calculate().Reverse()
-		moves += t.PutCol(c, l)
-		score += l.Score
+	for _, spawn := range r.Spawns {
+		t.appear(spawn.Cell)
 	}
-	return
 }
 
-//Line contains in from one row or column, Src it original position
-type Line struct {
-	Score int
-	Items [4]*Item
-	Src   [4]int
+//keyDirections is map of arrow keys to directions of move
+var keyDirections = map[glfw.Key]engine.Direction{
+	glfw.KeyLeft:  engine.Left,
+	glfw.KeyRight: engine.Right,
+	glfw.KeyUp:    engine.Up,
+	glfw.KeyDown:  engine.Down,
 }
 
-//GetRow get 4 items from specify row and return Line
-func (t *Table) GetRow(r int) (l *Line) {
-	l = new(Line)
-	r *= 4
-	for i := 0; i < 4; i++ {
-		l.Items[i] = t.Items[r+i]
-		l.Src[i] = r + i
-
+func keyCallback(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if action != glfw.Press {
+		return
 	}
-	return
-}
 
-//PutRow put line items to table by specify row
-func (t *Table) PutRow(r int, l *Line) (moves int) {
-	r *= 4
-	for i := 0; i < 4; i++ {
-		if t.Items[r+i] != l.Items[i] {
-			t.Items[r+i] = l.Items[i]
-			moves++
-		}
+	if playback.Active() {
+		playback.Key(key)
+		return
 	}
-	return
-}
 
-//GetCol get 4 items for specify column and return Line
-func (t *Table) GetCol(c int) (l *Line) {
-	l = new(Line)
-	for i := 0; i < 4; i++ {
-		l.Items[i] = t.Items[c+i*4]
-		l.Src[i] = c + i*4
+	if !header.conLB.Hidden {
+		header.KeyLeaderBoard(key)
+		return
 	}
-	return
-}
 
-//PutCol put line items to table by specify column
-func (t *Table) PutCol(c int, l *Line) (moves int) {
-	for i := 0; i < 4; i++ {
-		if t.Items[c+i*4] != l.Items[i] {
-			t.Items[c+i*4] = l.Items[i]
-			moves++
-		}
+	if key == glfw.KeyEscape {
+		w.SetShouldClose(true)
+		return
 	}
-	return
-}
-
-//Reverse line
-func (l *Line) Reverse() *Line {
-	l.Items[0], l.Items[1], l.Items[2], l.Items[3] = l.Items[3], l.Items[2], l.Items[1], l.Items[0]
-	l.Src[0], l.Src[1], l.Src[2], l.Src[3] = l.Src[3], l.Src[2], l.Src[1], l.Src[0]
-	return l
-}
-
-//Calculate is important function it move line items, always to left, calcluate item positions and values.
-func (l *Line) Calculate() *Line {
-	var offset int
-	var prev *Item
-
-	for i, item := range l.Items {
-		if item.N == 0 {
-			continue
-		}
 
-		offset, prev = l.LookupPrev(offset, i)
-		if prev == nil {
-			l.Move(offset, i)
-			continue
-		}
+	ctrl := mods&glfw.ModControl != 0
 
-		if prev.N != item.N {
-			offset++
-			l.Move(offset, i)
-			continue
+	//keys are typed to inputs of overlays, they should not change game, only Ctrl+S closes saved games
+	if !slots.Container.Hidden || !profiles.Container.Hidden {
+		if ctrl && key == glfw.KeyS && !slots.Container.Hidden {
+			slots.Toggle(nil)
 		}
-
-		prev.N = 0
-		item.N *= 2
-		l.Score += item.N
-		l.Move(offset, i)
-		offset++
+		return
 	}
 
-	return l
-}
-
-//LookupPrev lookup previous items in this line
-func (l *Line) LookupPrev(offset, count int) (int, *Item) {
-	for i := offset; i < count; i++ {
-		if l.Items[i].N != 0 {
-			return i, l.Items[i]
+	switch {
+	case key == glfw.KeyBackspace, ctrl && key == glfw.KeyZ && mods&glfw.ModShift == 0:
+		table.Undo()
+		return
+	case ctrl && key == glfw.KeyY, ctrl && key == glfw.KeyZ:
+		table.Redo()
+		return
+	case ctrl && key == glfw.KeyS:
+		slots.Toggle(nil)
+		return
+	case ctrl && key == glfw.KeyE:
+		filename, err := ExportPosition()
+		if err != nil {
+			notice.Show(fmt.Sprintf("Failed export position: %s", err))
+			return
 		}
-	}
-	return offset, nil
-}
-
-//Move - swap 2 items and prepare transition values
-func (l *Line) Move(dst, src int) {
-	if dst == src {
+		notice.Show(fmt.Sprintf("Position is exported to %s", filename))
 		return
 	}
-	i := l.Src[src]
-	row := float32(i / 4 * 25)
-	col := float32(i % 4 * 25)
-
-	l.Items[src].transition = true
-	l.Items[src].transSrc = TransSrc{Y: row, X: col, S: 25}
-	l.Items[dst], l.Items[src] = l.Items[src], l.Items[dst]
-	// l.I[dst], l.I[src] = l.I[src], l.I[dst]
-}
 
-func keyCallback(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-	if action != glfw.Press {
+	if table.lost || !victory.Container.Hidden {
 		return
 	}
 
-	if key == glfw.KeyEscape {
-		w.SetShouldClose(true)
+	d, ok := keyDirections[key]
+	if !ok {
 		return
 	}
 
-	if table.lost {
-		return
+	pm := table.State()
+
+	r := table.Play(d)
+	if r.Score > 0 {
+		header.AddScore(r.Score)
 	}
 
-	var moves, score int
+	if r.Moved() {
+		table.History.Push(pm)
+		table.Replay.Record(r)
 
-	var pm *TableState
+		d := table.played()
+		table.Duration += d
+		stats.Move(r, d)
+		table.Redraw()
 
-	if key == glfw.KeyLeft || key == glfw.KeyRight || key == glfw.KeyUp || key == glfw.KeyDown {
-		pm = table.TableState()
-		// for i, item := range table.Items {
-		// 	pm.Items[i] = new(Item)
-		// 	*pm.Items[i] = *item
-		// 	*pm.Items[i].btn = *item.btn
-		// }
-		// pm.Score = header.curr.Score
-	}
+		won := table.Won() && !table.keepPlaying
+		if won {
+			header.curr.Won = true
+		}
 
-	switch key {
-	case glfw.KeyLeft:
-		moves, score = table.MoveLeft()
-	case glfw.KeyRight:
-		moves, score = table.MoveRight()
-	case glfw.KeyUp:
-		moves, score = table.MoveUp()
-	case glfw.KeyDown:
-		moves, score = table.MoveDown()
-	case glfw.KeyBackspace:
-		if prevMove == nil {
+		//game over is evaluated right after spawn, when board has no legal moves
+		table.lost = !table.CanMove()
+		if table.lost {
+			GameOver()
 			return
 		}
-		table.RestoreState(prevMove)
-		prevMove = nil
-		return
-	}
 
-	if score > 0 {
-		header.AddScore(score)
-	}
+		if won {
+			victory.Show()
+		}
 
-	if moves > 0 {
-		prevMove = pm
-		table.FillRandomItem()
-		table.Redraw()
 		if err := SaveGame(); err != nil {
 			log.Println("failed save game")
 		}
-	} else {
-
-		for _, item := range table.Items {
-			if item.N == 0 {
-				table.lost = false
-				break
-			} else {
-				table.lost = true
-			}
-		}
-
-		if table.lost == true {
-			endgame.Show()
-		}
-
 	}
 }
 
 type EndGame struct {
 	Container *fizzgui.Container
 	Score     *fizzgui.Widget
+	Player    *fizzgui.Widget
 }
 
 //NewEndGame create lost/restart button
@@ -610,21 +736,28 @@ func NewEndGame() *EndGame {
 	e.Score.Style.TextColor = white
 	e.Score.Font = TextFontSmall
 
-	name := e.Container.NewText("Your name:")
-	name.Style.TextColor = white
-	name.Font = TextFontSmall
-	input := e.Container.NewInput("name", &header.curr.Name, nil)
-	input.Style.TextColor = white
-	input.Font = TextFont
+	e.Player = e.Container.NewText("")
+	e.Player.Layout.SetWidth("100%")
+	e.Player.TextAlign = fizzgui.TALIGN_CENTER
+	e.Player.Style.TextColor = white
+	e.Player.Font = TextFontSmall
 
 	restart := e.Container.NewButton("RESTART", NewGame)
-	restart.Layout.SetX("20%")
-	restart.Layout.SetWidth("60%")
+	restart.Layout.SetX("5%")
+	restart.Layout.SetWidth("43%")
 	restart.Layout.SetHeight("50px")
 	restart.Layout.PositionFixed = true
 	restart.Layout.VAlign = fizzgui.VAlignBottom
 	restart.Style.TextColor = white
 
+	replay := e.Container.NewButton("REPLAY", playback.Review)
+	replay.Layout.SetX("52%")
+	replay.Layout.SetWidth("43%")
+	replay.Layout.SetHeight("50px")
+	replay.Layout.PositionFixed = true
+	replay.Layout.VAlign = fizzgui.VAlignBottom
+	replay.Style.TextColor = white
+
 	return e
 }
 
@@ -632,15 +765,130 @@ func (e *EndGame) Hide() {
 	e.Container.Hidden = true
 }
 
+//Show overlay of ended game, game itself is finished by GameOver
 func (e *EndGame) Show() {
 	e.Container.Hidden = false
 	e.Score.Text = fmt.Sprintf("Your score: %d", header.curr.Score)
+	e.Player.Text = "Player: " + profile
 
-	saveFile.Truncate(0)
-	saveFile.Seek(0, 0)
+}
+
+//Victory is overlay shown when target tile is reached
+type Victory struct {
+	Container *fizzgui.Container
+	Score     *fizzgui.Widget
+}
+
+//NewVictory create victory overlay with continue and new game buttons
+func NewVictory() *Victory {
+	v := new(Victory)
+	v.Container = fizzgui.NewContainer("victory", "10%", "30%", "80%", "45%")
+	v.Container.Style.BackgroundColor = fizzgui.Color(236, 196, 0, 255)
+	v.Container.Zorder = 2
+	v.Container.Hidden = true
+
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	win := v.Container.NewText("You win!")
+	win.Layout.SetWidth("100%")
+	win.TextAlign = fizzgui.TALIGN_CENTER
+	win.Style.TextColor = white
+
+	v.Score = v.Container.NewText("")
+	v.Score.Layout.SetWidth("100%")
+	v.Score.TextAlign = fizzgui.TALIGN_CENTER
+	v.Score.Style.TextColor = white
+	v.Score.Font = TextFontSmall
+
+	cont := v.Container.NewButton("CONTINUE", v.Continue)
+	cont.Layout.SetX("5%")
+	cont.Layout.SetWidth("42%")
+	cont.Layout.SetHeight("50px")
+	cont.Layout.PositionFixed = true
+	cont.Layout.VAlign = fizzgui.VAlignBottom
+	cont.Style.TextColor = white
+	cont.Font = TextFontSmall
+
+	newgame := v.Container.NewButton("NEW GAME", NewGame)
+	newgame.Layout.SetX("53%")
+	newgame.Layout.SetWidth("42%")
+	newgame.Layout.SetHeight("50px")
+	newgame.Layout.PositionFixed = true
+	newgame.Layout.VAlign = fizzgui.VAlignBottom
+	newgame.Style.TextColor = white
+	newgame.Font = TextFontSmall
+
+	return v
+}
+
+func (v *Victory) Hide() {
+	v.Container.Hidden = true
+}
+
+func (v *Victory) Show() {
+	v.Container.Hidden = false
+	v.Score.Text = fmt.Sprintf("Tile %d reached, score: %d", table.Target, header.curr.Score)
+}
+
+//Continue hide overlay and keep playing current game, victory is not shown again in this game
+func (v *Victory) Continue(_ *fizzgui.Widget) {
+	v.Hide()
+	table.keepPlaying = true
+	if err := SaveGame(); err != nil {
+		log.Println("failed save game")
+	}
+}
+
+//Notice is overlay with warning message
+type Notice struct {
+	Container *fizzgui.Container
+	Text      *fizzgui.Widget
+}
+
+//NewNotice create warning overlay with OK button
+func NewNotice() *Notice {
+	n := new(Notice)
+	n.Container = fizzgui.NewContainer("notice", "10%", "30%", "80%", "45%")
+	n.Container.Style.BackgroundColor = fizzgui.Color(119, 110, 101, 255)
+	n.Container.Zorder = 4
+	n.Container.Hidden = true
+
+	white := fizzgui.Color(255, 255, 255, 255)
+
+	title := n.Container.NewText("Warning")
+	title.Layout.SetWidth("100%")
+	title.TextAlign = fizzgui.TALIGN_CENTER
+	title.Style.TextColor = white
+
+	n.Text = n.Container.NewText("")
+	n.Text.Layout.SetWidth("100%")
+	n.Text.Style.TextColor = white
+	n.Text.Font = TextFontSmall
+
+	ok := n.Container.NewButton("OK", n.Hide)
+	ok.Layout.SetX("20%")
+	ok.Layout.SetWidth("60%")
+	ok.Layout.SetHeight("50px")
+	ok.Layout.PositionFixed = true
+	ok.Layout.VAlign = fizzgui.VAlignBottom
+	ok.Style.TextColor = white
+
+	return n
+}
+
+func (n *Notice) Hide(_ *fizzgui.Widget) {
+	n.Container.Hidden = true
+}
+
+func (n *Notice) Show(text string) {
+	n.Container.Hidden = false
+	n.Text.Text = text
 }
 
 //Close it`s callback from renderLoop, should close application
 func Close() {
+	if err := stats.Save(); err != nil {
+		log.Println("failed save statistics,", err)
+	}
 	os.Exit(0)
 }
@@ -1,8 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/sg3des/2048/engine"
 )
 
 func init() {
@@ -17,65 +32,885 @@ func TestNewWindow(t *testing.T) {
 }
 
 func TestNewTable(t *testing.T) {
-	table = NewTable()
+	table = NewTable(engine.DefaultSize, engine.DefaultSize, 1)
 }
 
 func TestFillItem(t *testing.T) {
 	table.FillItem(1, 2)
-	if table.Items[1].N != 2 {
+	if table.Tiles[1].N != 2 || !table.Items[1].transition {
 		t.Fatal("failed fill item")
 	}
 }
 
-func TestMoveLeft(t *testing.T) {
-	table = NewTable()
-	table.FillItem(1, 2)
+func TestDecodeSave(t *testing.T) {
+	var buf bytes.Buffer
+	legacy := struct {
+		Items [16]int
+		Score int
+	}{Items: [16]int{2, 4}, Score: 16}
+	if err := gob.NewEncoder(&buf).Encode(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := DecodeSave(buf.Bytes())
+	if err != nil {
+		t.Fatal("failed migrate legacy save,", err)
+	}
+	if s.Version != saveVersion || s.Rows != 4 || s.Cols != 4 || s.Rules.Policy != engine.Even || s.Game.Score != 16 || s.Game.Cells[1] != 4 {
+		t.Fatalf("unexpected result of migration %+v", s)
+	}
+
+	data, err := EncodeSave(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = DecodeSave(data); err != nil || s.Game.Cells[0] != 2 {
+		t.Fatal("failed decode current save,", err)
+	}
+
+	s.Version = saveVersion + 1
+	data, _ = EncodeSave(s)
+	if _, err := DecodeSave(data); err == nil {
+		t.Fatal("save of newer version should not be decoded")
+	}
+}
+
+func TestWriteChecked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	legacy := func(data []byte) error {
+		if string(data) != "legacy" {
+			return errors.New("unknown format")
+		}
+		return nil
+	}
+
+	//backups are rotated on every write without interval
+	interval := backupInterval
+	backupInterval = 0
+	filename := filepath.Join(dir, "test.save")
+	for i := 1; i <= backupCount+2; i++ {
+		if err := WriteChecked(filename, []byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backupInterval = interval
 
-	table.MoveLeft()
-	if table.Items[0].N != 2 {
-		t.Error("failed move left")
+	data, from, err := ReadChecked(filename, legacy)
+	if err != nil || from != filename || string(data) != strconv.Itoa(backupCount+2) {
+		t.Fatalf("failed read last written data, %q from %s, %v", data, from, err)
 	}
-	if table.Items[1].N != 0 {
-		t.Error("failed move left")
+
+	if _, err := os.Stat(backupName(filename, backupCount+1)); !os.IsNotExist(err) {
+		t.Errorf("only %d backups should be kept", backupCount)
 	}
 
-	table.FillItem(3, 2)
+	//damaged file should be restored from the first backup
+	buf, _ := ioutil.ReadFile(filename)
+	buf[len(buf)-1]++
+	ioutil.WriteFile(filename, buf, 0644)
 
-	table.MoveLeft()
-	if table.Items[0].N != 4 {
-		t.Fatal("failed summ on 0 position")
+	data, from, err = ReadChecked(filename, legacy)
+	if err != nil || from != backupName(filename, 1) || string(data) != strconv.Itoa(backupCount+1) {
+		t.Fatalf("failed read data from backup, %q from %s, %v", data, from, err)
 	}
-	for i := 1; i < 16; i++ {
-		if table.Items[i].N != 0 {
-			t.Fatalf("item %d should be 0", i)
+
+	//file with damaged magic is not mistaken for legacy file
+	buf[0]++
+	ioutil.WriteFile(filename, buf, 0644)
+	if data, from, err = ReadChecked(filename, legacy); err != nil || from != backupName(filename, 1) {
+		t.Fatalf("file with damaged magic should be restored from backup, %q from %s, %v", data, from, err)
+	}
+
+	//backups are not rotated again before interval passes
+	for i := 0; i < 3; i++ {
+		if err := WriteChecked(filename, []byte("next")); err != nil {
+			t.Fatal(err)
 		}
 	}
+	if data, _, _ := ReadChecked(backupName(filename, 1), nil); string(data) != strconv.Itoa(backupCount+1) {
+		t.Errorf("first backup should be kept until interval passes, but it is %q", data)
+	}
 
-	table.FillItem(2, 4)
-	table.MoveLeft()
-	if table.Items[0].N != 8 {
-		t.Fatal("failed summ on 0 position to 8")
+	//file written without checksum is read as is
+	ioutil.WriteFile(filename, []byte("legacy"), 0644)
+	if data, _, err = ReadChecked(filename, legacy); err != nil || string(data) != "legacy" {
+		t.Fatalf("failed read legacy file, %q, %v", data, err)
 	}
 
-	for i := 1; i < 16; i++ {
-		if table.Items[i].N != 0 {
-			t.Fatalf("item %d should be 0", i)
+	//file is kept in place while it becomes the first backup
+	if err := rotateBackups(filename); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filename, backupName(filename, 1)} {
+		if data, err := ioutil.ReadFile(name); err != nil || string(data) != "legacy" {
+			t.Errorf("%s should contain file after rotation of backups, %q, %v", name, data, err)
 		}
 	}
+
+	if err := RemoveChecked(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadChecked(filename, legacy); !os.IsNotExist(err) {
+		t.Errorf("file and its backups should be removed, %v", err)
+	}
 }
 
-func TestMoveRight(t *testing.T) {
-	table = NewTable()
-	table.FillItem(0, 2)
-	table.FillItem(1, 2)
-	table.MoveRight()
-	if table.Items[3].N != 4 {
-		t.Error("failed move right, sum on 3 position should be 4")
+func TestSlots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(dir string) { slotsDir = dir }(slotsDir)
+	slotsDir = filepath.Join(dir, "saves")
+
+	if slots, err := ListSlots(); err != nil || len(slots) != 0 {
+		t.Fatalf("list of missing directory should be empty, %v, %v", slots, err)
 	}
 
+	s := &Save{
+		Magic:   saveMagic,
+		Version: saveVersion,
+		Time:    time.Now(),
+		Rows:    3,
+		Cols:    3,
+		Rules:   Rules{Policy: engine.Classic, Target: engine.DefaultTarget},
+		Game:    TableState{Cells: []int{2, 0, 0, 0, 64, 0, 0, 0, 8}, Score: 100},
+	}
+
+	if err := SaveSlot("first/game", s); err != nil {
+		t.Fatal(err)
+	}
+	s.Game.Score = 200
+	s.Time = s.Time.Add(-time.Hour)
+	if err := SaveSlot("second", s); err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := ListSlots()
+	if err != nil || len(slots) != 2 {
+		t.Fatalf("should be listed 2 slots, %v, %v", slots, err)
+	}
+	if slots[0].Name != "first_game" || slots[0].MaxTile != 64 || slots[0].Score != 100 {
+		t.Errorf("unexpected slot %+v", slots[0])
+	}
+
+	if err := RenameSlot("second", "first_game"); err == nil {
+		t.Error("rename to existing slot should fail")
+	}
+	if err := RenameSlot("second", "third"); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := LoadSlot("third"); err != nil || s.Game.Score != 200 {
+		t.Fatalf("failed load renamed slot, %v", err)
+	}
+
+	if err := DeleteSlot("third"); err != nil {
+		t.Fatal(err)
+	}
+	if slots, _ := ListSlots(); len(slots) != 1 {
+		t.Errorf("after delete should be listed 1 slot, but listed %d", len(slots))
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(dir string) { slotsDir = dir }(slotsDir)
+	slotsDir = filepath.Join(dir, "saves")
+
+	if err := checkSlotName("AutoSave"); err == nil {
+		t.Error("names of autosaves should be reserved")
+	}
+
+	s := &Save{
+		Magic:   saveMagic,
+		Version: saveVersion,
+		Rows:    3,
+		Cols:    3,
+		Rules:   Rules{Policy: engine.Classic, Target: engine.DefaultTarget},
+		Game:    TableState{Cells: []int{2, 0, 0, 0, 64, 0, 0, 0, 8}, Score: 100},
+	}
+	if err := SaveSlot("mine", s); err != nil {
+		t.Fatal(err)
+	}
+
+	//autosaves made within the same second do not replace each other, only the newest ones are kept
+	now := time.Now()
+	var last string
+	for i := 0; i < autosaveCount+2; i++ {
+		s.Time = now.Add(time.Duration(i) * time.Minute)
+		if last, err = Autosave(s, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slots, err := ListSlots()
+	if err != nil || len(slots) != autosaveCount+1 || slots[0].Name != last {
+		t.Fatalf("slot of player and %d newest autosaves should be kept, %v, %v", autosaveCount, slots, err)
+	}
+	if _, err := LoadSlot("mine"); err != nil {
+		t.Errorf("slot of player should be kept, %v", err)
+	}
+}
+
+func TestCreatePositionFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(d string) { positionsDir = d }(positionsDir)
+	positionsDir = dir
+
+	//positions exported within the same second are kept in separate files
+	now := time.Now()
+	names := make(map[string]bool)
 	for i := 0; i < 3; i++ {
-		if table.Items[i].N != 0 {
-			t.Errorf("failed move right, position %d should be 0, but whis is %d", i, table.Items[i].N)
+		f, err := createPositionFile(now)
+		if err != nil {
+			t.Fatal(err)
 		}
+		f.Close()
+		names[f.Name()] = true
+	}
+	if len(names) != 3 {
+		t.Errorf("every export should create new file, %v", names)
+	}
+}
+
+func TestMigrateDir(t *testing.T) {
+	old, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(old)
+
+	dir := filepath.Join(old, "data")
+	os.MkdirAll(dir, 0755)
+	os.MkdirAll(filepath.Join(old, "saves"), 0755)
+
+	for _, name := range []string{"2048.save", "leaderboard.1", filepath.Join("saves", "slot.save")} {
+		ioutil.WriteFile(filepath.Join(old, name), []byte(name), 0644)
+	}
+
+	migrateDir(old, dir)
+
+	for _, name := range []string{"2048.save", "leaderboard.1", filepath.Join("saves", "slot.save")} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != name {
+			t.Errorf("%s is not moved, %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(old, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed from old directory", name)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := SetupDirs(filepath.Join(dir, "data"), filepath.Join(dir, "config")); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dataDir, "2048.save"), []byte("save"), 0644)
+	ioutil.WriteFile(filepath.Join(dataDir, "statistics.1"), []byte("statistics"), 0644)
+
+	//files of previous versions are moved to default profile
+	name, err := SetupProfiles("")
+	if err != nil || name != defaultProfile {
+		t.Fatalf("default profile should be created, %s, %v", name, err)
+	}
+	if data, err := ioutil.ReadFile(saveFilename); err != nil || string(data) != "save" {
+		t.Errorf("save should be moved to default profile, %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(profilesDir, defaultProfile, "statistics.1")); err != nil {
+		t.Errorf("backup of statistics should be moved to default profile, %v", err)
+	}
+
+	if _, err := CreateProfile("Ann/2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateProfile("Ann_2"); err == nil {
+		t.Error("profile with the same name should not be created")
+	}
+
+	if name, _ := SetupProfiles("Ann_2"); name != "Ann_2" || filepath.Dir(statsFilename) != filepath.Join(profilesDir, "Ann_2") {
+		t.Errorf("remembered profile should be chosen, %s, statistics in %s", name, statsFilename)
+	}
+	if name, _ := SetupProfiles("Bob"); name != "Ann_2" {
+		t.Errorf("the first profile should be chosen if remembered one does not exist, but %s is chosen", name)
+	}
+
+	//personal best is kept in statistics of profile
+	s := LoadStats()
+	u, _ := playedResult(t, 3, 30)
+	s.RecordBest(u)
+	u.Score--
+	s.RecordBest(u)
+	if s = LoadStats(); s.Best[u.Mode().Key()] != u.Score+1 {
+		t.Errorf("personal best should be %d, but it is %d", u.Score+1, s.Best[u.Mode().Key()])
+	}
+}
+
+//playedResult play game of seed and return its result with replay
+func playedResult(t *testing.T, seed int64, moves int) (User, *engine.Board) {
+	b := engine.NewBoard(engine.DefaultSize, engine.DefaultSize, seed)
+	b.Start()
+
+	r := engine.NewReplay(b)
+	for i := 0; i < moves && b.CanMove(); i++ {
+		dirs := b.AvailableMoves()
+		r.Record(b.Play(dirs[i%len(dirs)]))
+	}
+
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return User{Score: b.Score, Name: "test", Rows: b.Rows, Cols: b.Cols, Replay: data, Rules: Rules{Undo: engine.DefaultUndoDepth}}, b
+}
+
+func TestVerifyUser(t *testing.T) {
+	u, b := playedResult(t, 5, 50)
+	u.MaxTile = 131072
+	if err := u.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if u.MaxTile != b.MaxTile() || u.Moves != 50 || u.Seed != 5 || u.Rules.Policy != engine.Classic {
+		t.Errorf("fields of result should be taken from replay, %+v", u)
+	}
+
+	edited := u
+	edited.Score += 4
+	if err := edited.Verify(); err == nil {
+		t.Error("result with edited score should not be verified")
+	}
+
+	edited = u
+	edited.Rules.Undo, edited.Undone = 0, true
+	if err := edited.Verify(); err == nil {
+		t.Error("result with undo used while it is disabled should not be verified")
+	}
+
+	edited = u
+	edited.Replay = nil
+	if err := edited.Verify(); err == nil {
+		t.Error("result without replay should not be verified")
+	}
+
+	lb := LeaderBoard{Users: []User{u, edited}}
+	if users := lb.Verified(); len(users) != 1 || users[0].Name != "test" {
+		t.Errorf("only verified result should be kept, %v", users)
+	}
+}
+
+func TestStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(filename string) { statsFilename = filename }(statsFilename)
+	statsFilename = filepath.Join(dir, "statistics")
+
+	s := LoadStats()
+	s.Move(&engine.MoveResult{Slides: []engine.Slide{{From: 1, To: 0, N: 2}}, Merges: []engine.Merge{{Cell: 0, N: 4}}}, time.Second)
+	s.Move(&engine.MoveResult{}, time.Second)
+	s.Undo()
+	s.Scores = []int{100, 20, 300, 40}
+
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = LoadStats()
+	if s.Moves != 1 || s.Merges[4] != 1 || s.Undos != 1 || s.TimePlayed != time.Second {
+		t.Fatalf("unexpected statistics %+v", s)
+	}
+	if s.AverageScore() != 115 || s.MedianScore() != 70 {
+		t.Errorf("average should be 115 and median 70, but they are %d and %d", s.AverageScore(), s.MedianScore())
+	}
+
+	//game is counted only if its replay has moves
+	tbl := &Table{Board: engine.NewBoard(engine.DefaultSize, engine.DefaultSize, 1)}
+	tbl.Start()
+	tbl.Replay = engine.NewReplay(tbl.Board)
+	s.FinishGame(tbl)
+	if s.GamesPlayed != 0 {
+		t.Errorf("game without moves should not be counted, %d games", s.GamesPlayed)
+	}
+
+	for _, d := range []engine.Direction{engine.Left, engine.Right, engine.Up, engine.Down} {
+		if res := tbl.Board.Play(d); res.Moved() {
+			tbl.Replay.Record(res)
+			break
+		}
+	}
+	s.FinishGame(tbl)
+	if s.GamesPlayed != 1 {
+		t.Errorf("game with moves should be counted, %d games", s.GamesPlayed)
+	}
+}
+
+func TestLeaderBoardModes(t *testing.T) {
+	classic := Rules{Policy: engine.Classic, Target: engine.DefaultTarget, Undo: 10}
+	hard := Rules{Policy: engine.Hard, Target: engine.DefaultTarget}
+
+	var lb LeaderBoard
+	for i := 1; i <= 15; i++ {
+		lb.Users = append(lb.Users, User{Score: i * 10, Rows: 4, Cols: 4, Rules: classic})
+	}
+	lb.Users = append(lb.Users, User{Score: 5, Rows: 4, Cols: 4, Rules: hard}, User{Score: 1000, Rows: 5, Cols: 5, Rules: classic})
+
+	limit := 10
+	lb.Trim(limit)
+	if len(lb.Users) != limit+2 {
+		t.Fatalf("leaderboard should keep %d results of every mode, but keeps %d", limit, len(lb.Users))
+	}
+
+	if modes := lb.Modes(); len(modes) != 3 {
+		t.Errorf("leaderboard should have 3 modes, but has %v", modes)
+	}
+
+	if best := lb.Best(NewMode(4, 4, classic)); best.Score != 150 {
+		t.Errorf("best score of 4x4 classic should be 150, but it is %d", best.Score)
+	}
+	if best := lb.Best(NewMode(4, 4, hard)); best.Score != 5 {
+		t.Errorf("best score of 4x4 hard should be 5, but it is %d", best.Score)
+	}
+	if best := lb.Best(NewMode(3, 3, hard)); best.Score != 0 {
+		t.Errorf("mode without results should not have best score, but it is %d", best.Score)
+	}
+}
+
+func TestLeaderBoardUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "leaderboard")
+
+	//two running games load leaderboard before any of them finishes game
+	var first, second LeaderBoard
+	a, _ := playedResult(t, 1, 30)
+	b, _ := playedResult(t, 2, 30)
+	a.Verify()
+	b.Verify()
+
+	first.Users = append(first.Users, a)
+	fi, err := first.Update(filename, leaderboardTop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second.Users = append(second.Users, b)
+	if _, err := second.Update(filename, leaderboardTop); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Update(filename, leaderboardTop); err != nil {
+		t.Fatal(err)
+	}
+
+	lb, err := ReadLeaderBoard(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lb.Users) != 2 || !lb.Contains(a) || !lb.Contains(b) {
+		t.Errorf("leaderboard file should contain results of both games once, %d results", len(lb.Users))
+	}
+
+	//results are kept beyond the 10 best ones shown in ranking
+	for seed := int64(3); seed < 15; seed++ {
+		u, _ := playedResult(t, seed, 10)
+		u.Verify()
+		second.Users = append(second.Users, u)
+	}
+	if _, err := second.Update(filename, leaderboardTop); err != nil {
+		t.Fatal(err)
+	}
+	if lb, err := ReadLeaderBoard(filename); err != nil || len(lb.Users) != 14 {
+		t.Errorf("leaderboard file should keep all 14 results, %d, %v", len(lb.Users), err)
+	}
+
+	//first game notices that file is changed by second one
+	current, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changedFile(fi, current) {
+		t.Error("change of leaderboard file should be noticed")
+	}
+}
+
+func TestLeaderBoardUnverified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "leaderboard")
+
+	//leaderboard of previous version has results without replays
+	legacy := User{Name: "old", Score: 4096, Date: time.Now().Add(-time.Hour)}
+	if err := (LeaderBoard{Users: []User{legacy}}).Write(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := playedResult(t, 1, 30)
+	u.Verify()
+	lb := LeaderBoard{Users: []User{u}}
+	for i := 0; i < 2; i++ {
+		if _, err := lb.Update(filename, leaderboardTop); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if lb, err := ReadLeaderBoard(filename); err != nil || len(lb.Users) != 1 || !lb.Contains(u) {
+		t.Errorf("leaderboard should rank only verified result, %v, %v", lb.Users, err)
+	}
+
+	kept, err := readLeaderBoard(unverifiedName(filename))
+	if err != nil || len(kept.Users) != 1 || kept.Users[0].Name != "old" || kept.Users[0].Score != 4096 {
+		t.Errorf("result without replay should be kept once in %s, %v, %v", unverifiedName(filename), kept.Users, err)
+	}
+
+	//results of own file are trusted, results of imported file are verified
+	edited := u
+	edited.Score += 4
+	if err := (LeaderBoard{Users: []User{edited}}).Write(filename); err != nil {
+		t.Fatal(err)
+	}
+	if lb, err := ReadLeaderBoard(filename); err != nil || len(lb.Users) != 1 {
+		t.Errorf("stored result should not be verified again, %v, %v", lb.Users, err)
+	}
+	if lb, err := ReadLeaderBoardFile(filename); err != nil || len(lb.Users) != 0 {
+		t.Errorf("edited result should not be imported, %v, %v", lb.Users, err)
+	}
+}
+
+func TestLeaderBoardExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var users []User
+	for seed := int64(1); seed <= 3; seed++ {
+		u, _ := playedResult(t, seed, 20+int(seed)*10)
+		u.Name = "player, " + strconv.Itoa(int(seed))
+		u.Date = time.Date(2020, 1, int(seed), 10, 0, 0, 0, time.UTC)
+		u.Duration = 90 * time.Second
+		u.Verify()
+		users = append(users, u)
+	}
+
+	//results of two machines have the second result in common
+	home, work := filepath.Join(dir, "home.csv"), filepath.Join(dir, "work.json")
+	if err := WriteLeaderBoardFile(home, LeaderBoard{users[:2]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLeaderBoardFile(work, LeaderBoard{users[1:]}); err != nil {
+		t.Fatal(err)
+	}
+
+	lb, err := ReadLeaderBoardFile(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lb.Users) != 2 || !bytes.Equal(lb.Users[0].Replay, users[0].Replay) || lb.Users[0].Name != users[0].Name ||
+		!lb.Users[0].Date.Equal(users[0].Date) || lb.Users[0].Duration != users[0].Duration || lb.Users[0].Rules != users[0].Rules {
+		t.Errorf("results should be read from CSV as they were written, %+v", lb.Users)
+	}
+
+	lb, err = ReadLeaderBoardFile(work)
+	if err != nil || len(lb.Users) != 2 || lb.Users[0].Duration != users[1].Duration {
+		t.Errorf("results should be read from JSON as they were written, %+v, %v", lb.Users, err)
+	}
+	if data, _ := ioutil.ReadFile(work); !bytes.Contains(data, []byte("\"duration\": 90\n")) {
+		t.Errorf("duration should be written to JSON in seconds, %s", data)
+	}
+
+	//columns which can be calculated from replay may be omitted
+	short := filepath.Join(dir, "short.csv")
+	csv := fmt.Sprintf("name,score,replay\n%s,%d,%s\n", "short", users[0].Score, base64.StdEncoding.EncodeToString(users[0].Replay))
+	ioutil.WriteFile(short, []byte(csv), 0644)
+	if lb, err := ReadLeaderBoardFile(short); err != nil || len(lb.Users) != 1 || lb.Users[0].Rows != users[0].Rows || lb.Users[0].Cols != users[0].Cols {
+		t.Errorf("result without size of board should be imported, %+v, %v", lb.Users, err)
+	}
+
+	lb, err = MergeLeaderBoardFiles([]string{home, work}, leaderboardTop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lb.Users) != 3 {
+		t.Fatalf("merged leaderboard should contain 3 results, but contains %d", len(lb.Users))
+	}
+	for i := 1; i < len(lb.Users); i++ {
+		if lb.Users[i-1].Score < lb.Users[i].Score {
+			t.Errorf("merged results should be ordered from the best one, %d before %d", lb.Users[i-1].Score, lb.Users[i].Score)
+		}
+	}
+
+	if lb, err := MergeLeaderBoardFiles([]string{home, work}, 1); err != nil || len(lb.Users) != 1 {
+		t.Errorf("merged leaderboard should keep only 1 result, %v", err)
+	}
+
+	edited := users[0]
+	edited.Score += 4
+	if err := WriteLeaderBoardFile(home, LeaderBoard{[]User{edited}}); err != nil {
+		t.Fatal(err)
+	}
+	if lb, err := ReadLeaderBoardFile(home); err != nil || len(lb.Users) != 0 {
+		t.Errorf("edited result should not be imported, %v", err)
+	}
+}
+
+func TestLeaderBoardFilter(t *testing.T) {
+	//wednesday
+	now := time.Date(2021, 3, 10, 15, 0, 0, 0, time.Local)
+	if start := ThisWeek.Start(now); !start.Equal(time.Date(2021, 3, 8, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("week should start on monday, but it starts on %s", start)
+	}
+
+	rules := Rules{Policy: engine.Classic, Target: engine.DefaultTarget, Undo: 10}
+	lb := LeaderBoard{[]User{
+		{Score: 100, Profile: "ann", Rows: 4, Cols: 4, Rules: rules, Date: now.Add(-time.Hour)},
+		{Score: 300, Profile: "ann", Rows: 4, Cols: 4, Rules: rules, Date: now.AddDate(0, 0, -1)},
+		{Score: 200, Profile: "bob", Rows: 4, Cols: 4, Rules: rules, Date: now.AddDate(0, 0, -2)},
+		{Score: 400, Name: "old", Rows: 4, Cols: 4, Rules: rules, Date: now.AddDate(0, -1, 0)},
+		{Score: 500, Profile: "ann", Rows: 5, Cols: 5, Rules: rules, Date: now.Add(-2 * time.Hour)},
+	}}
+
+	scores := func(f LeaderBoardFilter) (list []int) {
+		for _, u := range lb.Filter(f, now) {
+			list = append(list, u.Score)
+		}
+		return
+	}
+
+	for _, c := range []struct {
+		filter LeaderBoardFilter
+		scores string
+	}{
+		{LeaderBoardFilter{}, "[500 400 300 200 100]"},
+		{LeaderBoardFilter{Period: Today}, "[500 100]"},
+		{LeaderBoardFilter{Period: ThisWeek}, "[500 300 200 100]"},
+		{LeaderBoardFilter{Player: "ann"}, "[500 300 100]"},
+		{LeaderBoardFilter{Player: "old"}, "[400]"},
+		{LeaderBoardFilter{Mode: NewMode(4, 4, rules)}, "[400 300 200 100]"},
+		{LeaderBoardFilter{BestPerPlayer: true}, "[500 400 300 200]"},
+		{LeaderBoardFilter{Mode: NewMode(4, 4, rules), BestPerPlayer: true, Period: ThisWeek}, "[300 200]"},
+	} {
+		if list := fmt.Sprint(scores(c.filter)); list != c.scores {
+			t.Errorf("filter %+v should show %s, but shows %s", c.filter, c.scores, list)
+		}
+	}
+
+	if players := fmt.Sprint(lb.Players()); players != "[ann bob old]" {
+		t.Errorf("players should be [ann bob old], but they are %s", players)
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewServer(filepath.Join(dir, "leaderboard"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	u, _ := playedResult(t, 7, 40)
+	u.Verify()
+	r, err := SubmitResult(ts.URL, u)
+	if err != nil || r.Rank != 1 {
+		t.Fatalf("result should be accepted with rank 1, %+v, %v", r, err)
+	}
+
+	if r, err := SubmitResult(ts.URL, u); err == nil || r == nil || !r.Duplicate {
+		t.Error("the same result should not be accepted twice, it should be answered as duplicate")
+	}
+
+	edited, _ := playedResult(t, 8, 40)
+	edited.Score *= 2
+	if _, err := SubmitResult(ts.URL, edited); err == nil {
+		t.Error("edited result should be rejected")
+	}
+
+	//replay with huge length of steps should be rejected without harm to server
+	crafted := append([]byte("2048RPL1\x04\x04\x02\x07classic\x80\x10\x00\x01"), make([]byte, 16)...)
+	crafted = append(crafted, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01)
+	body, _ := json.Marshal(User{Score: 4, Rows: 4, Cols: 4, Replay: crafted})
+	resp, err := http.Post(ts.URL+"/api/submit", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("malformed replay should be rejected with status 400, but status is %s", resp.Status)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/leaderboard?mode=" + u.Mode().Key())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var users []User
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Score != u.Score || users[0].Replay != nil {
+		t.Errorf("leaderboard should contain accepted result without replay, %+v", users)
+	}
+
+	//results are kept after restart of server
+	if s, err = NewServer(filepath.Join(dir, "leaderboard"), 100); err != nil || len(s.Users) != 1 {
+		t.Errorf("server should load accepted result, %v", err)
+	}
+
+	//result which server failed to store is not kept, so it is accepted on retry
+	s.filename = filepath.Join(dir, "missing", "leaderboard")
+	other, _ := playedResult(t, 9, 40)
+	other.Verify()
+	body, _ = json.Marshal(other)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/submit", bytes.NewReader(body)))
+	if rec.Code != http.StatusInternalServerError || len(s.Users) != 1 {
+		t.Errorf("failed write should give status 500 and keep 1 result, but status is %d and there are %d results", rec.Code, len(s.Users))
+	}
+
+	if m, err := ParseMode(u.Mode().Key()); err != nil || m != u.Mode() {
+		t.Errorf("failed parse mode %s, %v", u.Mode().Key(), err)
+	}
+
+	//failure of server is not refusal of result, so submission is retried
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusInternalServerError, SubmitResponse{Error: "disk is full"})
+	}))
+	defer failing.Close()
+	if r, err := SubmitResult(failing.URL, u); err == nil || r != nil {
+		t.Errorf("failed server should give error without response, %+v, %v", r, err)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "submissions")
+	q := LoadQueue(filename)
+
+	q.submit = func(url string, u User) (*SubmitResponse, error) { return nil, errors.New("connection refused") }
+
+	now := time.Now()
+	good, bad := User{Score: 100, Replay: []byte{1}}, User{Score: -1, Replay: []byte{2}}
+	q.Items = []*Submission{{User: good, URL: "http://server", Status: Pending}, {User: bad, URL: "http://server", Status: Pending}}
+
+	q.Process(now)
+	if pending, _ := q.Counts(); pending != 2 || q.Items[0].NextTry != now.Add(minBackoff) {
+		t.Fatalf("unreachable server should keep submissions pending, %+v", q.Items[0])
+	}
+
+	//submissions are retried on later launch after backoff
+	q = LoadQueue(filename)
+	q.submit = func(url string, u User) (*SubmitResponse, error) { return nil, errors.New("too early") }
+	q.Process(now.Add(minBackoff / 2))
+	if q.Items[0].Attempts != 1 {
+		t.Fatal("submission should not be retried before backoff")
+	}
+
+	q.submit = func(url string, u User) (*SubmitResponse, error) {
+		if u.Score < 0 {
+			return &SubmitResponse{Error: "invalid"}, errors.New("rejected")
+		}
+		return &SubmitResponse{Accepted: true, Rank: 2}, nil
+	}
+	q.Process(now.Add(minBackoff))
+
+	if pending, accepted := q.Counts(); pending != 0 || accepted != 1 {
+		t.Errorf("should be 0 pending and 1 accepted submissions, but there are %d and %d", pending, accepted)
+	}
+	if q.Status(good) != "accepted, rank 2" || q.Status(bad) != "rejected: invalid" {
+		t.Errorf("unexpected statuses %q and %q", q.Status(good), q.Status(bad))
+	}
+
+	if backoff(3) != 4*minBackoff || backoff(100) != maxBackoff {
+		t.Errorf("unexpected backoff %s and %s", backoff(3), backoff(100))
+	}
+}
+
+func TestQueueShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	//two instances of game share one queue file
+	filename := filepath.Join(dir, "submissions")
+	q1, q2 := LoadQueue(filename), LoadQueue(filename)
+
+	first, second := User{Score: 100, Replay: []byte{1}}, User{Score: 200, Replay: []byte{2}}
+	q1.Items = append(q1.Items, &Submission{User: first, URL: "http://server", Status: Pending})
+	q1.save()
+	q2.Items = append(q2.Items, &Submission{User: second, URL: "http://server", Status: Pending})
+	q2.save()
+
+	if q := LoadQueue(filename); len(q.Items) != 2 {
+		t.Fatalf("both submissions should be kept in file, but there are %d", len(q.Items))
+	}
+
+	//first instance sends both results, second one sees them accepted and does not send them again
+	q1.submit = func(url string, u User) (*SubmitResponse, error) {
+		return &SubmitResponse{Accepted: true, Rank: 1}, nil
+	}
+	q1.Process(time.Now())
+
+	q2.submit = func(url string, u User) (*SubmitResponse, error) {
+		t.Errorf("result %d is sent twice", u.Score)
+		return nil, errors.New("sent twice")
+	}
+	q2.Process(time.Now())
+	if pending, accepted := q2.Counts(); pending != 0 || accepted != 2 {
+		t.Errorf("should be 0 pending and 2 accepted submissions, but there are %d and %d", pending, accepted)
+	}
+
+	//server which already has result accepts it
+	q3 := LoadQueue(filepath.Join(dir, "other"))
+	q3.Items = []*Submission{{User: first, URL: "http://server", Status: Pending}}
+	q3.submit = func(url string, u User) (*SubmitResponse, error) {
+		return &SubmitResponse{Error: "result is already submitted", Duplicate: true}, errors.New("rejected")
+	}
+	q3.Process(time.Now())
+	if q3.Status(first) != Accepted {
+		t.Errorf("already submitted result should be accepted, but it is %q", q3.Status(first))
 	}
 }
@@ -0,0 +1,469 @@
+//Package engine contains rules of 2048 game: board, moves, spawns, score and game over.
+//It does not depend on any graphics library, so it can be used by bots, servers and tests.
+package engine
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+//Limits and default value of board size, it is applied to count of rows and to count of columns
+const (
+	MinSize     = 3
+	MaxSize     = 8
+	DefaultSize = 4
+)
+
+//Limits and default value of target tile, reaching it wins the game
+const (
+	MinTarget     = 16
+	MaxTarget     = 131072
+	DefaultTarget = 2048
+)
+
+//ValidTarget return true if target is power of two between MinTarget and MaxTarget
+func ValidTarget(target int) bool {
+	return target >= MinTarget && target <= MaxTarget && target&(target-1) == 0
+}
+
+//Direction of move
+type Direction int
+
+//Possible directions of move
+const (
+	Left Direction = iota
+	Right
+	Up
+	Down
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Left:
+		return "left"
+	case Right:
+		return "right"
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
+//Tile is square with number on board, zero tile is empty cell
+type Tile struct {
+	N int
+}
+
+//Board is main struct contains matrix RowsxCols
+type Board struct {
+	Rows  int
+	Cols  int
+	Tiles []*Tile
+	Score int
+
+	//Seed is initial state of random numbers, board created with the same seed spawn the same tiles on the same moves
+	Seed int64
+
+	//Policy controls spawn of new tiles, by default it is Classic
+	Policy SpawnPolicy
+
+	//Target is value of tile which wins the game, by default it is DefaultTarget
+	Target int
+
+	src  *Source
+	rand *rand.Rand
+}
+
+//ValidSize return true if board with specified count of rows and columns may be created
+func ValidSize(rows, cols int) bool {
+	return rows >= MinSize && rows <= MaxSize && cols >= MinSize && cols <= MaxSize
+}
+
+//NewSeed return seed based on current time
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+//NewBoard initialize empty board rows x cols, both should be between MinSize and MaxSize
+func NewBoard(rows, cols int, seed int64) *Board {
+	if !ValidSize(rows, cols) {
+		panic(fmt.Sprintf("engine: invalid board size %dx%d", rows, cols))
+	}
+
+	b := &Board{
+		Rows:   rows,
+		Cols:   cols,
+		Tiles:  make([]*Tile, rows*cols),
+		Seed:   seed,
+		Policy: policies[Classic],
+		Target: DefaultTarget,
+		src:    NewSource(seed),
+	}
+	b.rand = rand.New(b.src)
+
+	for i := range b.Tiles {
+		b.Tiles[i] = new(Tile)
+	}
+
+	return b
+}
+
+//Cells return values of all tiles
+func (b *Board) Cells() (cells []int) {
+	cells = make([]int, len(b.Tiles))
+	for i, tile := range b.Tiles {
+		cells[i] = tile.N
+	}
+	return
+}
+
+//SetCells set values of all tiles, length of cells should be equal to count of tiles
+func (b *Board) SetCells(cells []int) {
+	if len(cells) != len(b.Tiles) {
+		panic(fmt.Sprintf("engine: %d cells for board %dx%d", len(cells), b.Rows, b.Cols))
+	}
+
+	for i, n := range cells {
+		b.Tiles[i].N = n
+	}
+}
+
+//RandState return current state of random numbers, it should be saved with board to continue the same spawn sequence
+func (b *Board) RandState() uint64 {
+	return b.src.State
+}
+
+//SetRandState restore state of random numbers returned by RandState
+func (b *Board) SetRandState(state uint64) {
+	b.src.State = state
+}
+
+//Set value to tile on board
+func (b *Board) Set(i, n int) {
+	b.Tiles[i].N = n
+}
+
+//Spawn fill empty cells with new tiles after move by spawn policy, return spawned tiles
+func (b *Board) Spawn() (spawns []Spawned) {
+	for n := b.Policy.Count(b); n > 0; n-- {
+		i := b.SpawnTile()
+		if i < 0 {
+			break
+		}
+		spawns = append(spawns, Spawned{Cell: i, N: b.Tiles[i].N})
+	}
+	return
+}
+
+//SpawnTile fill one empty cell chosen by spawn policy, return index of filled cell or -1 if board is full
+func (b *Board) SpawnTile() int {
+	empty := b.Empty()
+	if len(empty) == 0 {
+		return -1
+	}
+
+	i := b.Policy.Cell(b, b.rand, empty)
+	b.Set(i, b.Policy.Value(b.rand))
+	return i
+}
+
+//Empty return indexes of empty cells
+func (b *Board) Empty() (empty []int) {
+	for i, tile := range b.Tiles {
+		if tile.N == 0 {
+			empty = append(empty, i)
+		}
+	}
+	return
+}
+
+//Neighbours return indexes of cells adjacent to cell i by side
+func (b *Board) Neighbours(i int) (cells []int) {
+	row, col := i/b.Cols, i%b.Cols
+	if row > 0 {
+		cells = append(cells, i-b.Cols)
+	}
+	if row < b.Rows-1 {
+		cells = append(cells, i+b.Cols)
+	}
+	if col > 0 {
+		cells = append(cells, i-1)
+	}
+	if col < b.Cols-1 {
+		cells = append(cells, i+1)
+	}
+	return
+}
+
+//Full return true if there is no empty cells on board
+func (b *Board) Full() bool {
+	for _, tile := range b.Tiles {
+		if tile.N == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//MaxTile return the biggest value of tile on board
+func (b *Board) MaxTile() (max int) {
+	for _, tile := range b.Tiles {
+		if tile.N > max {
+			max = tile.N
+		}
+	}
+	return
+}
+
+//Won return true if target tile is reached
+func (b *Board) Won() bool {
+	return b.MaxTile() >= b.Target
+}
+
+//line return row or column as line ordered in direction of move
+func (b *Board) line(d Direction, i int) *Line {
+	switch d {
+	case Left:
+		return b.GetRow(i)
+	case Right:
+		return b.GetRow(i).Reverse()
+	case Up:
+		return b.GetCol(i)
+	default:
+		return b.GetCol(i).Reverse()
+	}
+}
+
+//CanMoveTo return true if move in specified direction changes board
+func (b *Board) CanMoveTo(d Direction) bool {
+	lines := b.Cols
+	if d == Left || d == Right {
+		lines = b.Rows
+	}
+
+	for i := 0; i < lines; i++ {
+		if b.line(d, i).CanMove() {
+			return true
+		}
+	}
+	return false
+}
+
+//AvailableMoves return directions in which tiles can be moved
+func (b *Board) AvailableMoves() (dirs []Direction) {
+	for _, d := range []Direction{Left, Right, Up, Down} {
+		if b.CanMoveTo(d) {
+			dirs = append(dirs, d)
+		}
+	}
+	return
+}
+
+//CanMove return true if there is at least one legal move, otherwise game is over
+func (b *Board) CanMove() bool {
+	return b.CanMoveTo(Left) || b.CanMoveTo(Right) || b.CanMoveTo(Up) || b.CanMoveTo(Down)
+}
+
+//Play move tiles in specified direction and spawn new tiles if board is changed
+func (b *Board) Play(d Direction) *MoveResult {
+	r := b.Move(d)
+	if r.Moved() {
+		r.Spawns = b.Spawn()
+	}
+	return r
+}
+
+//Move tiles in specified direction without spawn of new tiles, return slides, merges and earned score
+func (b *Board) Move(d Direction) *MoveResult {
+	r := &MoveResult{Direction: d}
+
+	lines := b.Cols
+	if d == Left || d == Right {
+		lines = b.Rows
+	}
+
+	for i := 0; i < lines; i++ {
+		l := b.line(d, i).Calculate()
+		if d == Right || d == Down {
+			l.Reverse()
+		}
+
+		if d == Left || d == Right {
+			b.PutRow(i, l)
+		} else {
+			b.PutCol(i, l)
+		}
+
+		r.Slides = append(r.Slides, l.Slides...)
+		r.Merges = append(r.Merges, l.Merges...)
+		r.Score += l.Score
+	}
+
+	b.Score += r.Score
+	return r
+}
+
+func (b *Board) MoveLeft() *MoveResult {
+	return b.Move(Left)
+}
+
+func (b *Board) MoveRight() *MoveResult {
+	return b.Move(Right)
+}
+
+func (b *Board) MoveUp() *MoveResult {
+	return b.Move(Up)
+}
+
+func (b *Board) MoveDown() *MoveResult {
+	return b.Move(Down)
+}
+
+//Line contains in from one row or column, Src it original position.
+//Slides and Merges are filled by Calculate.
+type Line struct {
+	Score  int
+	Tiles  []*Tile
+	Src    []int
+	Slides []Slide
+	Merges []Merge
+}
+
+//newLine create empty line with specified length
+func newLine(n int) *Line {
+	return &Line{
+		Tiles: make([]*Tile, n),
+		Src:   make([]int, n),
+	}
+}
+
+//GetRow get tiles from specify row and return Line
+func (b *Board) GetRow(r int) (l *Line) {
+	l = newLine(b.Cols)
+	r *= b.Cols
+	for i := 0; i < b.Cols; i++ {
+		l.Tiles[i] = b.Tiles[r+i]
+		l.Src[i] = r + i
+	}
+	return
+}
+
+//PutRow put line tiles to board by specify row
+func (b *Board) PutRow(r int, l *Line) (moves int) {
+	r *= b.Cols
+	for i := 0; i < b.Cols; i++ {
+		if b.Tiles[r+i] != l.Tiles[i] {
+			b.Tiles[r+i] = l.Tiles[i]
+			moves++
+		}
+	}
+	return
+}
+
+//GetCol get tiles for specify column and return Line
+func (b *Board) GetCol(c int) (l *Line) {
+	l = newLine(b.Rows)
+	for i := 0; i < b.Rows; i++ {
+		l.Tiles[i] = b.Tiles[c+i*b.Cols]
+		l.Src[i] = c + i*b.Cols
+	}
+	return
+}
+
+//PutCol put line tiles to board by specify column
+func (b *Board) PutCol(c int, l *Line) (moves int) {
+	for i := 0; i < b.Rows; i++ {
+		if b.Tiles[c+i*b.Cols] != l.Tiles[i] {
+			b.Tiles[c+i*b.Cols] = l.Tiles[i]
+			moves++
+		}
+	}
+	return
+}
+
+//Reverse line
+func (l *Line) Reverse() *Line {
+	for i, j := 0, len(l.Tiles)-1; i < j; i, j = i+1, j-1 {
+		l.Tiles[i], l.Tiles[j] = l.Tiles[j], l.Tiles[i]
+		l.Src[i], l.Src[j] = l.Src[j], l.Src[i]
+	}
+	return l
+}
+
+//Calculate is important function it move line tiles, always to left, calcluate tile positions and values.
+func (l *Line) Calculate() *Line {
+	var offset int
+	var prev *Tile
+
+	for i, tile := range l.Tiles {
+		if tile.N == 0 {
+			continue
+		}
+
+		offset, prev = l.LookupPrev(offset, i)
+		if prev == nil {
+			l.Move(offset, i)
+			continue
+		}
+
+		if prev.N != tile.N {
+			offset++
+			l.Move(offset, i)
+			continue
+		}
+
+		prev.N = 0
+		l.Move(offset, i)
+		tile.N *= 2
+		l.Score += tile.N
+		l.Merges = append(l.Merges, Merge{Cell: l.Src[offset], N: tile.N})
+		offset++
+	}
+
+	return l
+}
+
+//CanMove return true if tiles of line can be moved to left, it is when some tile has empty or equal cell before it
+func (l *Line) CanMove() bool {
+	for i := 1; i < len(l.Tiles); i++ {
+		prev, n := l.Tiles[i-1].N, l.Tiles[i].N
+		if n != 0 && (prev == 0 || prev == n) {
+			return true
+		}
+	}
+	return false
+}
+
+//LookupPrev lookup previous tiles in this line
+func (l *Line) LookupPrev(offset, count int) (int, *Tile) {
+	for i := offset; i < count; i++ {
+		if l.Tiles[i].N != 0 {
+			return i, l.Tiles[i]
+		}
+	}
+	return offset, nil
+}
+
+//Move - swap 2 tiles and remember slide of moved tile
+func (l *Line) Move(dst, src int) {
+	if dst == src {
+		return
+	}
+
+	l.Slides = append(l.Slides, Slide{From: l.Src[src], To: l.Src[dst], N: l.Tiles[src].N})
+	l.Tiles[dst], l.Tiles[src] = l.Tiles[src], l.Tiles[dst]
+}
+
+//Dump is print values of tiles RowsxCols to w
+func (b *Board) Dump(w io.Writer) {
+	for r := 0; r < b.Rows; r++ {
+		for c := 0; c < b.Cols; c++ {
+			fmt.Fprintf(w, "%2d ", b.Tiles[r*b.Cols+c].N)
+		}
+		fmt.Fprintln(w)
+	}
+}
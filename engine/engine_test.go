@@ -0,0 +1,304 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	b.Set(1, 2)
+	if b.Tiles[1].N != 2 {
+		t.Fatal("failed set tile")
+	}
+}
+
+func TestMoveLeft(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	b.Set(1, 2)
+
+	r := b.MoveLeft()
+	if b.Tiles[0].N != 2 {
+		t.Error("failed move left")
+	}
+	if b.Tiles[1].N != 0 {
+		t.Error("failed move left")
+	}
+	if len(r.Slides) != 1 || r.Slides[0] != (Slide{From: 1, To: 0, N: 2}) {
+		t.Errorf("unexpected slides %v", r.Slides)
+	}
+
+	b.Set(3, 2)
+
+	b.MoveLeft()
+	if b.Tiles[0].N != 4 {
+		t.Fatal("failed summ on 0 position")
+	}
+	for i := 1; i < 16; i++ {
+		if b.Tiles[i].N != 0 {
+			t.Fatalf("tile %d should be 0", i)
+		}
+	}
+
+	b.Set(2, 4)
+	b.MoveLeft()
+	if b.Tiles[0].N != 8 {
+		t.Fatal("failed summ on 0 position to 8")
+	}
+
+	for i := 1; i < 16; i++ {
+		if b.Tiles[i].N != 0 {
+			t.Fatalf("tile %d should be 0", i)
+		}
+	}
+
+	if b.Score != 12 {
+		t.Errorf("score should be 12, but this is %d", b.Score)
+	}
+}
+
+func TestMoveRight(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	b.Set(0, 2)
+	b.Set(1, 2)
+	b.MoveRight()
+	if b.Tiles[3].N != 4 {
+		t.Error("failed move right, sum on 3 position should be 4")
+	}
+
+	for i := 0; i < 3; i++ {
+		if b.Tiles[i].N != 0 {
+			t.Errorf("failed move right, position %d should be 0, but whis is %d", i, b.Tiles[i].N)
+		}
+	}
+}
+
+func TestMoveUpDown(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	b.SetCells([]int{
+		2, 0, 0, 0,
+		2, 0, 0, 0,
+		4, 0, 0, 0,
+		4, 0, 0, 0,
+	})
+
+	r := b.MoveUp()
+	if !r.Moved() || r.Score != 12 {
+		t.Fatalf("unexpected result of move up: slides %v, score %d", r.Slides, r.Score)
+	}
+	if b.Tiles[0].N != 4 || b.Tiles[4].N != 8 || b.Tiles[8].N != 0 {
+		t.Fatalf("failed move up, %v", b.Cells())
+	}
+
+	b.MoveDown()
+	if b.Tiles[12].N != 8 || b.Tiles[8].N != 4 || b.Tiles[0].N != 0 {
+		t.Fatalf("failed move down, %v", b.Cells())
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	for i := 0; i < 16; i++ {
+		if b.SpawnTile() < 0 {
+			t.Fatalf("failed spawn tile %d on not full board", i)
+		}
+	}
+
+	if !b.Full() {
+		t.Fatal("board should be full")
+	}
+	if b.SpawnTile() != -1 {
+		t.Fatal("spawn on full board should return -1")
+	}
+	if spawns := b.Spawn(); len(spawns) != 0 {
+		t.Fatalf("spawn on full board should not fill cells, but filled %v", spawns)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	for size := MinSize; size <= MaxSize; size++ {
+		b := NewBoard(size, size, 1)
+		if len(b.Tiles) != size*size {
+			t.Fatalf("board %dx%d should contains %d tiles, but contains %d", size, size, size*size, len(b.Tiles))
+		}
+
+		last := size*size - 1
+		b.Set(0, 2)
+		b.Set(last, 2)
+
+		b.MoveDown()
+		r := b.MoveRight()
+		if b.Tiles[last].N != 4 {
+			t.Fatalf("failed move on board %dx%d, %v", size, size, b.Cells())
+		}
+		if len(r.Slides) != 1 || r.Slides[0].From != size*size-size || r.Slides[0].To != last {
+			t.Errorf("tile should slide from %d to %d, but slides are %v", size*size-size, last, r.Slides)
+		}
+	}
+
+	if ValidSize(MinSize-1, DefaultSize) || ValidSize(DefaultSize, MaxSize+1) {
+		t.Error("sizes out of limits should be invalid")
+	}
+}
+
+func TestRectangular(t *testing.T) {
+	b := NewBoard(3, 5, 1)
+	if l := b.GetRow(0); len(l.Tiles) != 5 {
+		t.Fatalf("row of board 3x5 should contains 5 tiles, but contains %d", len(l.Tiles))
+	}
+	if l := b.GetCol(0); len(l.Tiles) != 3 {
+		t.Fatalf("column of board 3x5 should contains 3 tiles, but contains %d", len(l.Tiles))
+	}
+
+	b.SetCells([]int{
+		2, 0, 0, 0, 2,
+		0, 0, 4, 0, 0,
+		0, 0, 4, 0, 0,
+	})
+
+	b.MoveRight()
+	if b.Tiles[4].N != 4 || b.Tiles[9].N != 4 || b.Tiles[14].N != 4 {
+		t.Fatalf("failed move right on board 3x5, %v", b.Cells())
+	}
+
+	b.MoveUp()
+	if b.Tiles[4].N != 8 || b.Tiles[9].N != 4 || b.Tiles[14].N != 0 {
+		t.Fatalf("failed move up on board 3x5, %v", b.Cells())
+	}
+	if b.Score != 12 {
+		t.Errorf("score should be 12, but this is %d", b.Score)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	play := func(b *Board, moves int) {
+		for i := 0; i < moves; i++ {
+			b.Move(Direction(i % 4))
+			b.Spawn()
+		}
+	}
+
+	b1 := NewBoard(DefaultSize, DefaultSize, 2048)
+	b2 := NewBoard(DefaultSize, DefaultSize, 2048)
+	play(b1, 20)
+	play(b2, 20)
+
+	if fmt.Sprint(b1.Cells()) != fmt.Sprint(b2.Cells()) || b1.Score != b2.Score {
+		t.Fatalf("boards with the same seed should be equal, %v != %v", b1.Cells(), b2.Cells())
+	}
+
+	//restored board should continue the same spawn sequence
+	b3 := NewBoard(DefaultSize, DefaultSize, b1.Seed)
+	b3.SetCells(b1.Cells())
+	b3.Score = b1.Score
+	b3.SetRandState(b1.RandState())
+
+	play(b1, 20)
+	play(b3, 20)
+
+	if fmt.Sprint(b1.Cells()) != fmt.Sprint(b3.Cells()) || b1.Score != b3.Score {
+		t.Fatalf("restored board should continue the same game, %v != %v", b1.Cells(), b3.Cells())
+	}
+}
+
+func TestWon(t *testing.T) {
+	b := NewBoard(3, 3, 1)
+	b.Target = 16
+	b.SetCells([]int{
+		8, 8, 0,
+		0, 0, 0,
+		0, 0, 0,
+	})
+
+	if b.Won() {
+		t.Fatal("board without target tile should not be won")
+	}
+
+	b.MoveLeft()
+	if !b.Won() || b.MaxTile() != 16 {
+		t.Fatalf("board with target tile should be won, %v", b.Cells())
+	}
+
+	for target, valid := range map[int]bool{2048: true, 16: true, 8: false, 100: false, MaxTarget * 2: false} {
+		if ValidTarget(target) != valid {
+			t.Errorf("validity of target %d should be %v", target, valid)
+		}
+	}
+}
+
+func TestCanMove(t *testing.T) {
+	b := NewBoard(3, 3, 1)
+	b.SetCells([]int{
+		2, 4, 2,
+		4, 2, 4,
+		2, 4, 2,
+	})
+
+	if b.CanMove() || len(b.AvailableMoves()) != 0 {
+		t.Fatalf("full board without equal neighbours should not have moves, but has %v", b.AvailableMoves())
+	}
+
+	//full board with equal neighbours still can be moved
+	b.Set(4, 4)
+	if !b.CanMove() {
+		t.Fatal("full board with equal neighbours should have moves")
+	}
+	if dirs := b.AvailableMoves(); len(dirs) != 4 {
+		t.Fatalf("board should have moves in all directions, but has %v", dirs)
+	}
+
+	b.SetCells([]int{
+		2, 4, 0,
+		0, 0, 0,
+		0, 0, 0,
+	})
+	dirs := b.AvailableMoves()
+	if len(dirs) != 2 || dirs[0] != Right || dirs[1] != Down {
+		t.Fatalf("board should have moves only to right and down, but has %v", dirs)
+	}
+
+	for _, d := range []Direction{Left, Up} {
+		if b.Move(d).Moved() {
+			t.Fatalf("move %s should not change board", d)
+		}
+	}
+}
+
+func TestMoveResult(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	b.SetCells([]int{
+		2, 2, 4, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 8,
+	})
+
+	r := b.Play(Left)
+	if r.Direction != Left || r.Score != 4 {
+		t.Fatalf("unexpected direction %s or score %d", r.Direction, r.Score)
+	}
+
+	slides := []Slide{{From: 1, To: 0, N: 2}, {From: 2, To: 1, N: 4}, {From: 15, To: 12, N: 8}}
+	if fmt.Sprint(r.Slides) != fmt.Sprint(slides) {
+		t.Errorf("slides should be %v, but this is %v", slides, r.Slides)
+	}
+
+	if len(r.Merges) != 1 || r.Merges[0] != (Merge{Cell: 0, N: 4}) {
+		t.Errorf("unexpected merges %v", r.Merges)
+	}
+
+	if len(r.Spawns) != 1 || b.Tiles[r.Spawns[0].Cell].N != r.Spawns[0].N {
+		t.Errorf("move should spawn one tile, but spawns are %v", r.Spawns)
+	}
+
+	//move which does not change board does not spawn tiles
+	b.SetCells([]int{
+		2, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+	})
+	if r := b.Play(Left); r.Moved() || len(r.Spawns) != 0 {
+		t.Errorf("move to wall should not change board, but result is %v", r)
+	}
+}
@@ -0,0 +1,87 @@
+package engine
+
+//DefaultUndoDepth is default count of moves which can be undone
+const DefaultUndoDepth = 10
+
+//State is snapshot of board which can be restored
+type State struct {
+	Cells []int
+	Score int
+	Rand  uint64
+}
+
+//State return snapshot of current board
+func (b *Board) State() State {
+	return State{
+		Cells: b.Cells(),
+		Score: b.Score,
+		Rand:  b.RandState(),
+	}
+}
+
+//Restore board from snapshot
+func (b *Board) Restore(s State) {
+	b.SetCells(s.Cells)
+	b.Score = s.Score
+	b.SetRandState(s.Rand)
+}
+
+//History contains undo and redo stacks of board states, Past is stack of states before moves,
+//Future is stack of undone states. Depth limits count of stored states, zero depth disables undo.
+type History struct {
+	Depth  int
+	Past   []State
+	Future []State
+}
+
+//NewHistory create empty history with specified depth
+func NewHistory(depth int) *History {
+	return &History{Depth: depth}
+}
+
+//Push store state of board before move, it clears redo stack
+func (h *History) Push(s State) {
+	h.Future = nil
+	if h.Depth <= 0 {
+		return
+	}
+
+	h.Past = append(h.Past, s)
+	if len(h.Past) > h.Depth {
+		h.Past = append(h.Past[:0], h.Past[len(h.Past)-h.Depth:]...)
+	}
+}
+
+//Undo return previous state and store current state to redo stack, ok is false if there is nothing to undo
+func (h *History) Undo(current State) (s State, ok bool) {
+	if len(h.Past) == 0 {
+		return
+	}
+
+	s = h.Past[len(h.Past)-1]
+	h.Past = h.Past[:len(h.Past)-1]
+	h.Future = append(h.Future, current)
+	return s, true
+}
+
+//Redo return undone state and store current state to undo stack, ok is false if there is nothing to redo
+func (h *History) Redo(current State) (s State, ok bool) {
+	if len(h.Future) == 0 {
+		return
+	}
+
+	s = h.Future[len(h.Future)-1]
+	h.Future = h.Future[:len(h.Future)-1]
+	h.Past = append(h.Past, current)
+	return s, true
+}
+
+//CanUndo return true if there is state to undo
+func (h *History) CanUndo() bool {
+	return len(h.Past) > 0
+}
+
+//CanRedo return true if there is state to redo
+func (h *History) CanRedo() bool {
+	return len(h.Future) > 0
+}
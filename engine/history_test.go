@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	h := NewHistory(2)
+
+	b.SpawnTile()
+	var states []State
+	for _, d := range []Direction{Left, Right, Up, Down} {
+		states = append(states, b.State())
+		h.Push(b.State())
+		b.Move(d)
+		b.Spawn()
+	}
+
+	if len(h.Past) != 2 {
+		t.Fatalf("history should be limited by depth 2, but contains %d states", len(h.Past))
+	}
+
+	last := b.State()
+	for i := 3; i >= 2; i-- {
+		s, ok := h.Undo(b.State())
+		if !ok {
+			t.Fatalf("undo %d should be possible", i)
+		}
+		b.Restore(s)
+		if b.Score != states[i].Score || !equalCells(b.Cells(), states[i].Cells) {
+			t.Fatalf("undo should restore state before move %d", i)
+		}
+	}
+
+	if _, ok := h.Undo(b.State()); ok {
+		t.Fatal("undo deeper than depth should not be possible")
+	}
+
+	for h.CanRedo() {
+		s, _ := h.Redo(b.State())
+		b.Restore(s)
+	}
+	if b.RandState() != last.Rand || !equalCells(b.Cells(), last.Cells) {
+		t.Fatal("redo should restore last state")
+	}
+
+	h.Undo(b.State())
+	h.Push(b.State())
+	if h.CanRedo() {
+		t.Fatal("new move should clear redo stack")
+	}
+
+	h = NewHistory(0)
+	h.Push(b.State())
+	if h.CanUndo() {
+		t.Fatal("history with zero depth should not undo")
+	}
+}
+
+func equalCells(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//Position is human-readable description of board, it is written as JSON with one line per row of tiles:
+//
+//	{
+//	  "rows": 3,
+//	  "cols": 3,
+//	  "score": 4,
+//	  "seed": 1,
+//	  "tiles": [
+//	    [4, 0, 0],
+//	    [0, 0, 2],
+//	    [0, 0, 0]
+//	  ]
+//	}
+//
+//Rand, Policy and Target are optional, missing Rand means that spawns start from Seed.
+type Position struct {
+	Rows   int     `json:"rows"`
+	Cols   int     `json:"cols"`
+	Score  int     `json:"score"`
+	Seed   int64   `json:"seed"`
+	Rand   uint64  `json:"rand,omitempty"`
+	Policy string  `json:"policy,omitempty"`
+	Target int     `json:"target,omitempty"`
+	Tiles  [][]int `json:"tiles"`
+}
+
+//Position return description of current board
+func (b *Board) Position() *Position {
+	p := &Position{
+		Rows:   b.Rows,
+		Cols:   b.Cols,
+		Score:  b.Score,
+		Seed:   b.Seed,
+		Rand:   b.RandState(),
+		Policy: b.Policy.Name(),
+		Target: b.Target,
+	}
+
+	cells := b.Cells()
+	for row := 0; row < b.Rows; row++ {
+		p.Tiles = append(p.Tiles, cells[row*b.Cols:(row+1)*b.Cols])
+	}
+
+	return p
+}
+
+//Cells return values of tiles row by row
+func (p *Position) Cells() (cells []int) {
+	for _, row := range p.Tiles {
+		cells = append(cells, row...)
+	}
+	return
+}
+
+//Validate check size of board and values of tiles, missing size is taken from tiles
+func (p *Position) Validate() error {
+	if p.Rows == 0 && p.Cols == 0 && len(p.Tiles) > 0 {
+		p.Rows, p.Cols = len(p.Tiles), len(p.Tiles[0])
+	}
+
+	if !ValidSize(p.Rows, p.Cols) {
+		return fmt.Errorf("invalid board size %dx%d", p.Rows, p.Cols)
+	}
+
+	if len(p.Tiles) != p.Rows {
+		return fmt.Errorf("board %dx%d contains %d rows of tiles", p.Rows, p.Cols, len(p.Tiles))
+	}
+
+	for i, row := range p.Tiles {
+		if len(row) != p.Cols {
+			return fmt.Errorf("row %d contains %d tiles, but board has %d columns", i+1, len(row), p.Cols)
+		}
+		for _, n := range row {
+			if n != 0 && (n < 2 || n&(n-1) != 0) {
+				return fmt.Errorf("invalid tile %d in row %d", n, i+1)
+			}
+		}
+	}
+
+	if p.Policy != "" {
+		if _, ok := Policy(p.Policy); !ok {
+			return fmt.Errorf("unknown spawn policy %q", p.Policy)
+		}
+	}
+
+	if p.Target != 0 && !ValidTarget(p.Target) {
+		return fmt.Errorf("invalid target %d", p.Target)
+	}
+
+	if p.Score < 0 {
+		return fmt.Errorf("negative score %d", p.Score)
+	}
+
+	return nil
+}
+
+//Board create board from position, position should be valid
+func (p *Position) Board() *Board {
+	b := NewBoard(p.Rows, p.Cols, p.Seed)
+	b.SetCells(p.Cells())
+	b.Score = p.Score
+
+	if p.Rand != 0 {
+		b.SetRandState(p.Rand)
+	}
+	if p.Policy != "" {
+		b.Policy, _ = Policy(p.Policy)
+	}
+	if p.Target != 0 {
+		b.Target = p.Target
+	}
+
+	return b
+}
+
+//ReadPosition decode and validate position
+func ReadPosition(r io.Reader) (*Position, error) {
+	p := new(Position)
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+
+	return p, p.Validate()
+}
+
+//WritePosition encode position as indented JSON, every row of tiles is written in one line
+func WritePosition(w io.Writer, p *Position) error {
+	tiles := p.Tiles
+	p.Tiles = nil
+	head, err := json.MarshalIndent(p, "", "  ")
+	p.Tiles = tiles
+	if err != nil {
+		return err
+	}
+
+	//tiles is the last field, so encoded position ends with null tiles which are replaced by rows
+	if !bytes.HasSuffix(head, []byte("null\n}")) {
+		return fmt.Errorf("engine: failed encode tiles of position")
+	}
+	head = bytes.TrimSuffix(head, []byte("null\n}"))
+
+	var rows []string
+	for _, row := range tiles {
+		nums := make([]string, len(row))
+		for i, n := range row {
+			nums[i] = strconv.Itoa(n)
+		}
+		rows = append(rows, "    ["+strings.Join(nums, ", ")+"]")
+	}
+
+	_, err = fmt.Fprintf(w, "%s[\n%s\n  ]\n}\n", head, strings.Join(rows, ",\n"))
+	return err
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	b := NewBoard(3, 4, 7)
+	b.SetCells([]int{
+		2, 0, 0, 4,
+		0, 8, 0, 0,
+		0, 0, 0, 2,
+	})
+	b.Score = 12
+	b.Play(Left)
+
+	var buf bytes.Buffer
+	if err := WritePosition(&buf, b.Position()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\n    [2, 4, 0, 0],\n") {
+		t.Errorf("rows of tiles should be written in one line, %s", buf.String())
+	}
+
+	p, err := ReadPosition(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//restored board should continue the same game
+	r := p.Board()
+	b.Play(Up)
+	r.Play(Up)
+	if fmt.Sprint(b.Cells()) != fmt.Sprint(r.Cells()) || b.Score != r.Score {
+		t.Fatalf("restored board should be equal, %v != %v", b.Cells(), r.Cells())
+	}
+}
+
+func TestReadPosition(t *testing.T) {
+	p, err := ReadPosition(strings.NewReader(`{"seed": 5, "tiles": [[2,0,0],[0,4,0],[0,0,0]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows != 3 || p.Cols != 3 || p.Board().Tiles[4].N != 4 {
+		t.Errorf("size should be taken from tiles, %+v", p)
+	}
+
+	for _, s := range []string{
+		`{"tiles": [[2,0],[0,4]]}`,
+		`{"tiles": [[2,0,0],[0,4],[0,0,0]]}`,
+		`{"tiles": [[3,0,0],[0,0,0],[0,0,0]]}`,
+		`{"rows": 4, "cols": 4, "tiles": [[2,0,0],[0,0,0],[0,0,0]]}`,
+		`{"policy": "unknown", "tiles": [[2,0,0],[0,0,0],[0,0,0]]}`,
+	} {
+		if _, err := ReadPosition(strings.NewReader(s)); err == nil {
+			t.Errorf("position %s should be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+//replayMagic starts encoded replay
+const replayMagic = "2048RPL1"
+
+//ErrReplay is returned when replay is damaged or does not match rules of engine
+var ErrReplay = errors.New("engine: invalid replay")
+
+//Step is one recorded move: pressed direction and tiles spawned after it
+type Step struct {
+	Direction Direction
+	Spawns    []Spawned
+}
+
+//Replay is record of game: initial board with seed and rules, and every move with its spawns.
+//Undone moves are removed from Steps, so replay always leads to current board.
+type Replay struct {
+	Rows   int
+	Cols   int
+	Seed   int64
+	Policy string
+	Target int
+
+	//Cells, Score and Rand describe board before the first step
+	Cells []int
+	Score int
+	Rand  uint64
+
+	Steps []Step
+
+	//Undone is stack of undone steps, they are restored by Redo
+	Undone []Step
+}
+
+//NewReplay start record of game from current state of board
+func NewReplay(b *Board) *Replay {
+	return &Replay{
+		Rows:   b.Rows,
+		Cols:   b.Cols,
+		Seed:   b.Seed,
+		Policy: b.Policy.Name(),
+		Target: b.Target,
+		Cells:  b.Cells(),
+		Score:  b.Score,
+		Rand:   b.RandState(),
+	}
+}
+
+//Record append result of move, moves which do not change board are not recorded
+func (r *Replay) Record(res *MoveResult) {
+	if !res.Moved() {
+		return
+	}
+
+	r.Steps = append(r.Steps, Step{Direction: res.Direction, Spawns: res.Spawns})
+	r.Undone = nil
+}
+
+//Undo remove the last step, it should be called on every undo of board
+func (r *Replay) Undo() bool {
+	if len(r.Steps) == 0 {
+		return false
+	}
+
+	r.Undone = append(r.Undone, r.Steps[len(r.Steps)-1])
+	r.Steps = r.Steps[:len(r.Steps)-1]
+	return true
+}
+
+//Redo restore the last undone step
+func (r *Replay) Redo() bool {
+	if len(r.Undone) == 0 {
+		return false
+	}
+
+	r.Steps = append(r.Steps, r.Undone[len(r.Undone)-1])
+	r.Undone = r.Undone[:len(r.Undone)-1]
+	return true
+}
+
+//Board create board in state before the first step
+func (r *Replay) Board() (*Board, error) {
+	if !ValidSize(r.Rows, r.Cols) || len(r.Cells) != r.Rows*r.Cols {
+		return nil, fmt.Errorf("%s, board %dx%d with %d cells", ErrReplay, r.Rows, r.Cols, len(r.Cells))
+	}
+
+	policy, ok := Policy(r.Policy)
+	if !ok {
+		return nil, fmt.Errorf("%s, unknown spawn policy %q", ErrReplay, r.Policy)
+	}
+
+	b := NewBoard(r.Rows, r.Cols, r.Seed)
+	b.Policy = policy
+	b.Target = r.Target
+	b.SetCells(r.Cells)
+	b.Score = r.Score
+	b.SetRandState(r.Rand)
+
+	return b, nil
+}
+
+//Apply play i-th step on board and check that board spawned the same tiles as recorded
+func (r *Replay) Apply(b *Board, i int) (*MoveResult, error) {
+	step := r.Steps[i]
+
+	res := b.Play(step.Direction)
+	if !res.Moved() {
+		return res, fmt.Errorf("%s, step %d: move %s does not change board", ErrReplay, i+1, step.Direction)
+	}
+
+	if !sameSpawns(res.Spawns, step.Spawns) {
+		return res, fmt.Errorf("%s, step %d: spawned %v, but recorded %v", ErrReplay, i+1, res.Spawns, step.Spawns)
+	}
+
+	return res, nil
+}
+
+//sameSpawns return true if both moves spawned the same tiles in the same order
+func sameSpawns(a, b []Spawned) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Final play all steps and return resulting board
+func (r *Replay) Final() (*Board, error) {
+	b, err := r.Board()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range r.Steps {
+		if _, err := r.Apply(b, i); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+//log2 return power of two of tile value, 0 for empty cell
+func log2(n int) byte {
+	var p byte
+	for n > 1 {
+		n >>= 1
+		p++
+	}
+	return p
+}
+
+//pow2 return tile value of power of two, 0 for empty cell
+func pow2(p byte) int {
+	if p == 0 {
+		return 0
+	}
+	return 1 << p
+}
+
+//MarshalBinary encode replay compactly: numbers are varints, tiles are powers of two,
+//every step takes one byte plus two bytes per spawned tile
+func (r *Replay) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(replayMagic)
+
+	num := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(v uint64) { buf.Write(num[:binary.PutUvarint(num, v)]) }
+
+	putUvarint(uint64(r.Rows))
+	putUvarint(uint64(r.Cols))
+	buf.Write(num[:binary.PutVarint(num, r.Seed)])
+	putUvarint(uint64(len(r.Policy)))
+	buf.WriteString(r.Policy)
+	putUvarint(uint64(r.Target))
+	putUvarint(uint64(r.Score))
+	putUvarint(r.Rand)
+
+	for _, n := range r.Cells {
+		buf.WriteByte(log2(n))
+	}
+
+	for _, steps := range [][]Step{r.Steps, r.Undone} {
+		putUvarint(uint64(len(steps)))
+		for _, step := range steps {
+			if len(step.Spawns) > 63 {
+				return nil, fmt.Errorf("engine: step with %d spawns can not be encoded", len(step.Spawns))
+			}
+
+			buf.WriteByte(byte(step.Direction) | byte(len(step.Spawns))<<2)
+			for _, s := range step.Spawns {
+				buf.WriteByte(byte(s.Cell))
+				buf.WriteByte(log2(s.N))
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+//UnmarshalBinary decode replay encoded by MarshalBinary
+func (r *Replay) UnmarshalBinary(data []byte) (err error) {
+	if !bytes.HasPrefix(data, []byte(replayMagic)) {
+		return ErrReplay
+	}
+	buf := bytes.NewReader(data[len(replayMagic):])
+
+	uvarint := func() int {
+		v, e := binary.ReadUvarint(buf)
+		if e != nil {
+			err = ErrReplay
+		}
+		return int(v)
+	}
+
+	//length read count of following bytes or items, every item takes at least one byte,
+	//so count is checked against rest of data before anything is allocated
+	length := func() int {
+		v, e := binary.ReadUvarint(buf)
+		if e != nil || v > uint64(buf.Len()) {
+			err = ErrReplay
+			return 0
+		}
+		return int(v)
+	}
+
+	readByte := func() byte {
+		b, e := buf.ReadByte()
+		if e != nil {
+			err = ErrReplay
+		}
+		return b
+	}
+
+	r.Rows = uvarint()
+	r.Cols = uvarint()
+	if r.Seed, err = binary.ReadVarint(buf); err != nil {
+		return ErrReplay
+	}
+
+	n := length()
+	if err != nil {
+		return ErrReplay
+	}
+	policy := make([]byte, n)
+	buf.Read(policy)
+	r.Policy = string(policy)
+
+	r.Target = uvarint()
+	r.Score = uvarint()
+	rand, e := binary.ReadUvarint(buf)
+	if e != nil || err != nil || !ValidSize(r.Rows, r.Cols) {
+		return ErrReplay
+	}
+	r.Rand = rand
+
+	r.Cells = make([]int, r.Rows*r.Cols)
+	for i := range r.Cells {
+		r.Cells[i] = pow2(readByte())
+	}
+
+	for _, steps := range []*[]Step{&r.Steps, &r.Undone} {
+		count := length()
+		if err != nil {
+			return ErrReplay
+		}
+
+		*steps = make([]Step, count)
+		for i := range *steps {
+			b := readByte()
+			step := Step{Direction: Direction(b & 3)}
+			for j := 0; j < int(b>>2); j++ {
+				step.Spawns = append(step.Spawns, Spawned{Cell: int(readByte()), N: pow2(readByte())})
+			}
+			(*steps)[i] = step
+		}
+	}
+
+	if err == nil && buf.Len() != 0 {
+		err = ErrReplay
+	}
+	return err
+}
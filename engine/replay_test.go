@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 42)
+	b.Policy, _ = Policy(Hard)
+	b.Spawn()
+	b.Spawn()
+
+	r := NewReplay(b)
+	for i := 0; i < 30; i++ {
+		moves := b.AvailableMoves()
+		r.Record(b.Play(moves[i%len(moves)]))
+	}
+
+	//undone step is not a part of replay until it is redone
+	cells := b.Cells()
+	r.Record(b.Play(b.AvailableMoves()[0]))
+	r.Undo()
+	if r.Redo(); !r.Undo() || len(r.Undone) != 1 {
+		t.Fatal("failed undo step of replay")
+	}
+
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > 3*len(r.Steps)+100 {
+		t.Errorf("replay of %d steps takes %d bytes", len(r.Steps), len(data))
+	}
+
+	d := new(Replay)
+	if err := d.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := d.Final()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(f.Cells()) != fmt.Sprint(cells) || f.Policy.Name() != Hard {
+		t.Fatalf("replay should lead to the same board, %v != %v", f.Cells(), cells)
+	}
+
+	//tampered spawn should be detected
+	d.Steps[len(d.Steps)/2].Spawns[0].N ^= 6
+	if _, err := d.Final(); err == nil {
+		t.Error("replay with changed spawn should be invalid")
+	}
+
+	if err := d.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("truncated replay should not be decoded")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	b := NewBoard(3, 4, 99)
+	b.Target = 64
+	b.Start()
+
+	r := NewReplay(b)
+	for i := 0; i < 20 && b.CanMove(); i++ {
+		moves := b.AvailableMoves()
+		r.Record(b.Play(moves[i%len(moves)]))
+	}
+
+	f, err := Verify(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Score != b.Score || fmt.Sprint(f.Cells()) != fmt.Sprint(b.Cells()) {
+		t.Fatalf("verified board should be equal to played one, %v != %v", f.Cells(), b.Cells())
+	}
+
+	//replay started from edited board is not trusted
+	r.Cells[0] = 1024
+	if _, err := Verify(r); err == nil {
+		t.Error("replay which does not start from new game should be rejected")
+	}
+
+	r.Cells = NewReplay(NewBoard(3, 4, 99)).Cells
+	if _, err := Verify(r); err == nil {
+		t.Error("replay started from empty board should be rejected")
+	}
+}
+
+func TestUnmarshalCrafted(t *testing.T) {
+	header := func(policyLen uint64, policy string, steps uint64) []byte {
+		buf := []byte(replayMagic)
+		num := make([]byte, binary.MaxVarintLen64)
+		for _, v := range []uint64{2, 2, 2, policyLen} {
+			buf = append(buf, num[:binary.PutUvarint(num, v)]...)
+		}
+		buf = append(buf, policy...)
+		for _, v := range []uint64{2048, 0, 1} {
+			buf = append(buf, num[:binary.PutUvarint(num, v)]...)
+		}
+		buf = append(buf, 1, 0, 0, 1)
+		return append(buf, num[:binary.PutUvarint(num, steps)]...)
+	}
+
+	//huge lengths should be rejected before allocation
+	for _, data := range [][]byte{
+		header(1<<63, "", 0),
+		header(1<<46, "", 0),
+		header(1<<64-1, "", 0),
+		header(7, "classic", 1<<63),
+		header(7, "classic", 1<<46),
+		header(7, "classic", 1<<64-1),
+	} {
+		if err := new(Replay).UnmarshalBinary(data); err == nil {
+			t.Errorf("crafted replay %x should not be decoded", data)
+		}
+	}
+
+	//damaged replays should be rejected without panic
+	b := NewBoard(DefaultSize, DefaultSize, 7)
+	b.Start()
+	r := NewReplay(b)
+	for i := 0; i < 20 && b.CanMove(); i++ {
+		r.Record(b.Play(b.AvailableMoves()[0]))
+	}
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range data {
+		new(Replay).UnmarshalBinary(data[:i])
+		for _, v := range []byte{0, 0x7f, 0x80, 0xff} {
+			damaged := append([]byte(nil), data...)
+			damaged[i] = v
+			if d := new(Replay); d.UnmarshalBinary(damaged) == nil {
+				d.Final()
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package engine
+
+//Slide is movement of tile from one cell to another, N is value of tile before merge
+type Slide struct {
+	From int
+	To   int
+	N    int
+}
+
+//Merge is join of two equal tiles in cell, N is value of resulting tile
+type Merge struct {
+	Cell int
+	N    int
+}
+
+//Spawned is new tile appeared in cell
+type Spawned struct {
+	Cell int
+	N    int
+}
+
+//MoveResult describes one move: slides of every moved tile, merges and spawned tiles.
+//It is the only description of move which renderers, recorders and bots need.
+type MoveResult struct {
+	Direction Direction
+	Slides    []Slide
+	Merges    []Merge
+	Spawns    []Spawned
+	Score     int
+}
+
+//Moved return true if move changed board, only such moves spawn new tiles
+func (r *MoveResult) Moved() bool {
+	return len(r.Slides) > 0
+}
@@ -0,0 +1,31 @@
+package engine
+
+//Source is deterministic source of random numbers, unlike sources of math/rand its state can be saved and restored.
+//It implements splitmix64 algorithm.
+type Source struct {
+	State uint64
+}
+
+//NewSource create source initialized by seed
+func NewSource(seed int64) *Source {
+	return &Source{State: uint64(seed)}
+}
+
+//Seed initialize source by seed
+func (s *Source) Seed(seed int64) {
+	s.State = uint64(seed)
+}
+
+//Uint64 return next pseudo-random 64-bit value
+func (s *Source) Uint64() uint64 {
+	s.State += 0x9e3779b97f4a7c15
+	z := s.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+//Int63 return next pseudo-random non-negative 63-bit value
+func (s *Source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
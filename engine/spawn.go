@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"math/rand"
+)
+
+//Names of built-in spawn policies
+const (
+	Classic = "classic"
+	Even    = "even"
+	Hard    = "hard"
+)
+
+//SpawnPolicy controls how new tiles appear on board after move
+type SpawnPolicy interface {
+	//Name is unique name of policy, it is stored in saves
+	Name() string
+
+	//Value return number of new tile
+	Value(r *rand.Rand) int
+
+	//Count return count of tiles spawned after move
+	Count(b *Board) int
+
+	//Cell choose index of cell for new tile from not empty list of empty cells
+	Cell(b *Board, r *rand.Rand, empty []int) int
+}
+
+var (
+	policies    = make(map[string]SpawnPolicy)
+	policyNames []string
+)
+
+func init() {
+	RegisterPolicy(classicPolicy{})
+	RegisterPolicy(evenPolicy{})
+	RegisterPolicy(hardPolicy{})
+}
+
+//RegisterPolicy add policy to list of known policies, policy with the same name is replaced
+func RegisterPolicy(p SpawnPolicy) {
+	if _, ok := policies[p.Name()]; !ok {
+		policyNames = append(policyNames, p.Name())
+	}
+	policies[p.Name()] = p
+}
+
+//Policy return registered policy by name
+func Policy(name string) (SpawnPolicy, bool) {
+	p, ok := policies[name]
+	return p, ok
+}
+
+//PolicyNames return names of registered policies in order of registration
+func PolicyNames() []string {
+	return append([]string(nil), policyNames...)
+}
+
+//Weight is relative probability of tile value
+type Weight struct {
+	N      int
+	Weight int
+}
+
+//Weighted choose tile value by weights
+func Weighted(r *rand.Rand, weights ...Weight) int {
+	var total int
+	for _, w := range weights {
+		total += w.Weight
+	}
+
+	n := r.Intn(total)
+	for _, w := range weights {
+		if n < w.Weight {
+			return w.N
+		}
+		n -= w.Weight
+	}
+
+	return weights[len(weights)-1].N
+}
+
+//classicPolicy spawn one tile, 2 with probability 90% and 4 with 10%, in uniformly random empty cell
+type classicPolicy struct{}
+
+func (classicPolicy) Name() string {
+	return Classic
+}
+
+func (classicPolicy) Value(r *rand.Rand) int {
+	return Weighted(r, Weight{2, 9}, Weight{4, 1})
+}
+
+func (classicPolicy) Count(*Board) int {
+	return 1
+}
+
+func (classicPolicy) Cell(_ *Board, r *rand.Rand, empty []int) int {
+	return empty[r.Intn(len(empty))]
+}
+
+//evenPolicy spawn one tile, 2 or 4 with equal probability, in uniformly random empty cell
+type evenPolicy struct {
+	classicPolicy
+}
+
+func (evenPolicy) Name() string {
+	return Even
+}
+
+func (evenPolicy) Value(r *rand.Rand) int {
+	if r.Uint32()%2 == 0 {
+		return 2
+	}
+	return 4
+}
+
+//hardPolicy spawn one tile, 2 with probability 75% and 4 with 25%,
+//in empty cell with the least count of neighbours which can be merged with 2 or 4
+type hardPolicy struct {
+	classicPolicy
+}
+
+func (hardPolicy) Name() string {
+	return Hard
+}
+
+func (hardPolicy) Value(r *rand.Rand) int {
+	return Weighted(r, Weight{2, 3}, Weight{4, 1})
+}
+
+func (hardPolicy) Cell(b *Board, r *rand.Rand, empty []int) int {
+	var cells []int
+	min := -1
+
+	for _, i := range empty {
+		var n int
+		for _, j := range b.Neighbours(i) {
+			if v := b.Tiles[j].N; v == 2 || v == 4 {
+				n++
+			}
+		}
+
+		if min < 0 || n < min {
+			min = n
+			cells = cells[:0]
+		}
+		if n == min {
+			cells = append(cells, i)
+		}
+	}
+
+	return cells[r.Intn(len(cells))]
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPolicies(t *testing.T) {
+	for _, name := range []string{Classic, Even, Hard} {
+		p, ok := Policy(name)
+		if !ok {
+			t.Fatalf("policy %s is not registered", name)
+		}
+		if p.Name() != name {
+			t.Errorf("policy %s has name %s", name, p.Name())
+		}
+	}
+
+	if _, ok := Policy("unknown"); ok {
+		t.Error("unknown policy should not be found")
+	}
+}
+
+func TestPolicyValues(t *testing.T) {
+	r := rand.New(NewSource(1))
+
+	for name, want := range map[string]int{Classic: 10, Even: 50, Hard: 25} {
+		p, _ := Policy(name)
+
+		var fours int
+		for i := 0; i < 10000; i++ {
+			switch p.Value(r) {
+			case 2:
+			case 4:
+				fours++
+			default:
+				t.Fatalf("policy %s spawn unexpected value", name)
+			}
+		}
+
+		if percent := fours / 100; percent < want-3 || percent > want+3 {
+			t.Errorf("policy %s spawn 4 in %d%% cases, expected about %d%%", name, percent, want)
+		}
+	}
+}
+
+func TestHardPolicyCell(t *testing.T) {
+	b := NewBoard(3, 3, 1)
+	b.SetCells([]int{
+		0, 2, 0,
+		4, 8, 8,
+		0, 8, 0,
+	})
+
+	p, _ := Policy(Hard)
+	for i := 0; i < 10; i++ {
+		if cell := p.Cell(b, b.rand, b.Empty()); cell != 8 {
+			t.Fatalf("hard policy should choose cell 8 without small neighbours, but choose %d", cell)
+		}
+	}
+}
+
+func TestBoardPolicy(t *testing.T) {
+	b := NewBoard(DefaultSize, DefaultSize, 1)
+	if b.Policy.Name() != Classic {
+		t.Fatalf("default policy should be %s, but this is %s", Classic, b.Policy.Name())
+	}
+
+	b.Policy, _ = Policy(Even)
+	if cells := b.Spawn(); len(cells) != 1 {
+		t.Fatalf("policy %s should spawn one tile, but spawned %d", Even, len(cells))
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"fmt"
+)
+
+//StartTiles is count of tiles spawned on empty board at start of new game
+const StartTiles = 2
+
+//Start spawn initial tiles of new game
+func (b *Board) Start() {
+	for i := 0; i < StartTiles; i++ {
+		b.SpawnTile()
+	}
+}
+
+//Verify re-simulate replay from new game and return final board. Replay should start from empty board
+//with StartTiles spawned by its seed and policy, every move should be legal and spawn the same tiles as recorded.
+//Score of returned board is calculated by engine, so it can be trusted unlike score reported by player.
+func Verify(r *Replay) (*Board, error) {
+	start, err := r.Board()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ValidTarget(r.Target) {
+		return nil, fmt.Errorf("%s, invalid target %d", ErrReplay, r.Target)
+	}
+
+	b := NewBoard(r.Rows, r.Cols, r.Seed)
+	b.Policy = start.Policy
+	b.Target = r.Target
+	b.Start()
+
+	if !sameTiles(b, start) || b.RandState() != start.RandState() || start.Score != 0 {
+		return nil, fmt.Errorf("%s, replay does not start from new game of seed %d", ErrReplay, r.Seed)
+	}
+
+	for i := range r.Steps {
+		if _, err := r.Apply(b, i); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+//sameTiles return true if boards have the same values of tiles
+func sameTiles(a, b *Board) bool {
+	if len(a.Tiles) != len(b.Tiles) {
+		return false
+	}
+	for i := range a.Tiles {
+		if a.Tiles[i].N != b.Tiles[i].N {
+			return false
+		}
+	}
+	return true
+}